@@ -0,0 +1,266 @@
+package soydepend
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+var benchSizes = []int{1_000, 10_000}
+
+// syntheticGraph returns a random DAG of n nodes, where each node i
+// depends on up to degree random nodes before it. Edges are added directly
+// to the store, so that building large graphs does not dominate benchmarks.
+func syntheticGraph(n, degree int, seed int64) Graph[int] {
+	rng := rand.New(rand.NewSource(seed))
+	g := New[int]()
+
+	for i := 0; i < n; i++ {
+		g.AddNode(i)
+		if i == 0 {
+			continue
+		}
+
+		for j := 0; j < degree; j++ {
+			dependency := rng.Intn(i)
+			g.store.SetEdge(i, dependency, g.store.EdgeKind(i, dependency)|EdgeKind(1<<rng.Intn(4)))
+		}
+	}
+
+	return g
+}
+
+// layersClone is the previous implementation of Layers, which clones g
+// and repeatedly deletes leaves. It is kept as a reference for tests and benchmarks.
+func layersClone[T comparable](g *Graph[T], kinds ...EdgeKind) []Set[T] {
+	var layers []Set[T]
+	copied := g.Clone()
+
+	if mask := kindMask(kinds, KindAll); mask != KindAll {
+		for dependent, dependencies := range copied.GraphDependencies() {
+			for dependency := range dependencies {
+				if copied.EdgeKindOf(dependent, dependency)&mask == 0 {
+					_ = copied.Undepend(dependent, dependency)
+				}
+			}
+		}
+	}
+
+	for copied.store.Len() != 0 {
+		leaves := copied.Leaves()
+
+		for leaf := range leaves {
+			copied.Delete(leaf)
+		}
+
+		layers = append(layers, copyMap(leaves))
+	}
+
+	return layers
+}
+
+// syntheticEdges returns dependent->dependency edges of a random DAG of n nodes
+// in random order, so that they are not added in topological order.
+func syntheticEdges(n, degree int, seed int64) [][2]int {
+	rng := rand.New(rand.NewSource(seed))
+	perm := rng.Perm(n) // Hide the topological order in node names
+
+	edges := make([][2]int, 0, n*degree)
+	for i := 1; i < n; i++ {
+		for j := 0; j < degree; j++ {
+			edges = append(edges, [2]int{perm[i], perm[rng.Intn(i)]})
+		}
+	}
+
+	rng.Shuffle(len(edges), func(i, j int) { edges[i], edges[j] = edges[j], edges[i] })
+
+	return edges
+}
+
+// dependNaive is the previous implementation of Depend, which searches
+// the whole dependency closure of dependency for every new edge.
+// It is kept as a reference for tests and benchmarks, and does not maintain topological order.
+func dependNaive[T comparable](g *Graph[T], dependent, dependency T) error {
+	if dependent == dependency {
+		return ErrDependsOnSelf
+	}
+
+	if path := g.pathDeep(g.store.RangeDependencies, dependency, KindAll, equal(dependent)); path != nil {
+		return &CycleError[T]{Path: append(path, dependency)}
+	}
+
+	g.store.SetEdge(dependent, dependency, KindRuntime)
+
+	return nil
+}
+
+func TestDependReference(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		g, naive := New[int](), New[int]()
+
+		for i := 0; i < 1000; i++ {
+			dependent, dependency := rng.Intn(200), rng.Intn(200)
+
+			err := g.Depend(dependent, dependency)
+			expected := dependNaive(&naive, dependent, dependency)
+
+			if (err == nil) != (expected == nil) {
+				t.Fatalf("seed %d: depend(%d, %d) returned %v, expecting %v", seed, dependent, dependency, err, expected)
+			}
+
+			// Shortest cycles are found either way, though not necessarily the same one
+			var errCycle, expectedCycle *CycleError[int]
+			if errors.As(err, &errCycle) && errors.As(expected, &expectedCycle) && len(errCycle.Path) != len(expectedCycle.Path) {
+				t.Fatalf("seed %d: unexpected cycle %v, expecting %v", seed, err, expected)
+			}
+		}
+
+		g.AssertRelationships()
+
+		for i := 0; i < 1000; i++ {
+			dependent, dependency := rng.Intn(200), rng.Intn(200)
+			if g.DependsOn(dependent, dependency) != naive.Dependencies(dependent).Contains(dependency) {
+				t.Fatalf("seed %d: DependsOn(%d, %d) differs from reference", seed, dependent, dependency)
+			}
+		}
+	}
+}
+
+func TestLayersReference(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		g := syntheticGraph(500, 3, seed)
+		g.AssertRelationships()
+
+		for _, kinds := range [][]EdgeKind{nil, {KindBuild}, {KindRuntime, KindTest}} {
+			expected := layersClone(&g, kinds...)
+			if layers := g.Layers(kinds...); !reflect.DeepEqual(layers, expected) {
+				t.Fatalf("seed %d kinds %v: layers differ from reference", seed, kinds)
+			}
+		}
+	}
+}
+
+func BenchmarkLayers(b *testing.B) {
+	for _, n := range append(benchSizes, 200_000) {
+		g := syntheticGraph(n, 4, 1)
+
+		b.Run(fmt.Sprintf("kahn/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.Layers()
+			}
+		})
+	}
+
+	for _, n := range benchSizes {
+		g := syntheticGraph(n, 4, 1)
+
+		b.Run(fmt.Sprintf("clone/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				layersClone(&g)
+			}
+		})
+	}
+}
+
+func BenchmarkBulkDepend(b *testing.B) {
+	for _, n := range benchSizes {
+		edges := syntheticEdges(n, 4, 1)
+
+		b.Run(fmt.Sprintf("incremental/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g := New[int]()
+				for _, edge := range edges {
+					if err := g.Depend(edge[0], edge[1]); err != nil {
+						b.Fatal("unexpected error:", err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("naive/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g := New[int]()
+				for _, edge := range edges {
+					if err := dependNaive(&g, edge[0], edge[1]); err != nil {
+						b.Fatal("unexpected error:", err)
+					}
+				}
+			}
+		})
+
+		// Each edge adds a new dependency to the deepest node of the chain so far
+		b.Run(fmt.Sprintf("chain/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g := New[int]()
+				for j := 0; j < n; j++ {
+					if err := g.Depend(j, j+1); err != nil {
+						b.Fatal("unexpected error:", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkStoreMemory reports heap retained by graphs with string nodes
+func BenchmarkStoreMemory(b *testing.B) {
+	edges := syntheticEdges(100_000, 4, 1)
+
+	stores := []struct {
+		name string
+		new  func() Graph[string]
+	}{
+		{name: "map", new: New[string]},
+		{name: "compact", new: NewCompact[string]},
+	}
+
+	for _, s := range stores {
+		b.Run(s.name, func(b *testing.B) {
+			var before, after runtime.MemStats
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				g := s.new()
+				for _, edge := range edges {
+					if err := g.Depend(strconv.Itoa(edge[0]), strconv.Itoa(edge[1])); err != nil {
+						b.Fatal("unexpected error:", err)
+					}
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(g)
+			}
+
+			b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(len(edges)), "heap-bytes/edge")
+		})
+	}
+}
+
+func BenchmarkDependencies(b *testing.B) {
+	for _, n := range benchSizes {
+		edges := syntheticEdges(n, 4, 1)
+		for _, g := range []Graph[int]{New[int](), NewCompact[int]()} {
+			for _, edge := range edges {
+				_ = g.Depend(edge[0], edge[1])
+			}
+
+			name := "map"
+			if _, ok := g.store.(*compactStore[int]); ok {
+				name = "compact"
+			}
+
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					g.Dependencies(i % n)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package soydepend
+
+import "slices"
+
+// NewCompact returns a new graph that interns nodes to dense integer IDs,
+// and keeps edges as slices sorted by ID instead of nested maps.
+// It uses much less memory than New on large graphs, and deep traversals
+// like Dependencies use bitsets instead of maps to track visited nodes.
+//
+// IDs of deleted nodes are not reused until Realloc compacts the graph.
+func NewCompact[T comparable]() Graph[T] {
+	return newGraph[T](newCompactStore[T]())
+}
+
+// NewCompactStore returns the store used by NewCompact.
+// It implements ClosureStore.
+func NewCompactStore[T comparable]() Store[T] {
+	return newCompactStore[T]()
+}
+
+// compactStore is a store with nodes interned to dense uint32 IDs
+type compactStore[T comparable] struct {
+	ids          map[T]uint32
+	keys         []T             // ID -> node, zero value for deleted IDs
+	live         bitset          // IDs of nodes in store
+	dependencies [][]compactEdge // ID -> edges to dependencies, sorted by ID
+	dependents   [][]compactEdge // ID -> edges to dependents, sorted by ID
+}
+
+type compactEdge struct {
+	id   uint32
+	kind EdgeKind
+}
+
+type bitset []uint64
+
+func newCompactStore[T comparable]() *compactStore[T] {
+	return &compactStore[T]{ids: make(map[T]uint32)}
+}
+
+func (s *compactStore[T]) AddNode(node T)      { s.intern(node) }
+func (s *compactStore[T]) HasNode(node T) bool { return contains(s.ids, node) }
+func (s *compactStore[T]) Len() int            { return len(s.ids) }
+
+// intern returns ID of node, adding node if it is not already in store
+func (s *compactStore[T]) intern(node T) uint32 {
+	if id, ok := s.ids[node]; ok {
+		return id
+	}
+
+	id := uint32(len(s.keys))
+	s.ids[node] = id
+	s.keys = append(s.keys, node)
+	s.dependencies = append(s.dependencies, nil)
+	s.dependents = append(s.dependents, nil)
+	s.live.set(id)
+
+	return id
+}
+
+func (s *compactStore[T]) DeleteNode(node T) {
+	id, ok := s.ids[node]
+	if !ok {
+		return
+	}
+
+	for _, edge := range s.dependencies[id] {
+		s.dependents[edge.id] = removeCompactEdge(s.dependents[edge.id], id)
+	}
+
+	for _, edge := range s.dependents[id] {
+		s.dependencies[edge.id] = removeCompactEdge(s.dependencies[edge.id], id)
+	}
+
+	var zero T
+	s.keys[id] = zero
+	s.dependencies[id] = nil
+	s.dependents[id] = nil
+	s.live.clear(id)
+	delete(s.ids, node)
+}
+
+// RangeNodes calls f with nodes in the order they were added
+func (s *compactStore[T]) RangeNodes(f func(node T) bool) {
+	for id := range s.keys {
+		if s.live.has(uint32(id)) && !f(s.keys[id]) {
+			return
+		}
+	}
+}
+
+func (s *compactStore[T]) SetEdge(dependent, dependency T, kind EdgeKind) {
+	from, to := s.intern(dependent), s.intern(dependency)
+	s.dependencies[from] = setCompactEdge(s.dependencies[from], to, kind)
+	s.dependents[to] = setCompactEdge(s.dependents[to], from, kind)
+}
+
+func (s *compactStore[T]) RemoveEdge(dependent, dependency T) {
+	from, ok := s.ids[dependent]
+	if !ok {
+		return
+	}
+
+	to, ok := s.ids[dependency]
+	if !ok {
+		return
+	}
+
+	s.dependencies[from] = removeCompactEdge(s.dependencies[from], to)
+	s.dependents[to] = removeCompactEdge(s.dependents[to], from)
+}
+
+func (s *compactStore[T]) EdgeKind(dependent, dependency T) EdgeKind {
+	from, ok := s.ids[dependent]
+	if !ok {
+		return 0
+	}
+
+	to, ok := s.ids[dependency]
+	if !ok {
+		return 0
+	}
+
+	edges := s.dependencies[from]
+	if i, found := searchCompactEdge(edges, to); found {
+		return edges[i].kind
+	}
+
+	return 0
+}
+
+func (s *compactStore[T]) RangeDependencies(node T, f func(dependency T, kind EdgeKind) bool) {
+	if id, ok := s.ids[node]; ok {
+		s.rangeEdges(s.dependencies[id], f)
+	}
+}
+
+func (s *compactStore[T]) RangeDependents(node T, f func(dependent T, kind EdgeKind) bool) {
+	if id, ok := s.ids[node]; ok {
+		s.rangeEdges(s.dependents[id], f)
+	}
+}
+
+func (s *compactStore[T]) rangeEdges(edges []compactEdge, f func(node T, kind EdgeKind) bool) {
+	for _, edge := range edges {
+		if !f(s.keys[edge.id], edge.kind) {
+			return
+		}
+	}
+}
+
+func (s *compactStore[T]) NumDependencies(node T) int {
+	if id, ok := s.ids[node]; ok {
+		return len(s.dependencies[id])
+	}
+
+	return 0
+}
+
+func (s *compactStore[T]) NumDependents(node T) int {
+	if id, ok := s.ids[node]; ok {
+		return len(s.dependents[id])
+	}
+
+	return 0
+}
+
+func (s *compactStore[T]) DependencyClosure(node T, mask EdgeKind) Set[T] {
+	return s.closure(s.dependencies, node, mask)
+}
+
+func (s *compactStore[T]) DependentClosure(node T, mask EdgeKind) Set[T] {
+	return s.closure(s.dependents, node, mask)
+}
+
+// closure walks edges from node with a bitset of visited IDs,
+// following only edges with kinds in mask
+func (s *compactStore[T]) closure(edges [][]compactEdge, node T, mask EdgeKind) Set[T] {
+	id, ok := s.ids[node]
+	if !ok {
+		return nil
+	}
+
+	visited := make(bitset, (len(s.keys)+63)/64)
+	queue := []uint32{id}
+
+	var found []uint32
+	for len(queue) != 0 {
+		current := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		for _, edge := range edges[current] {
+			if edge.kind&mask == 0 || visited.has(edge.id) {
+				continue
+			}
+
+			visited.set(edge.id)
+			found = append(found, edge.id)
+			queue = append(queue, edge.id)
+		}
+	}
+
+	results := make(Set[T], len(found))
+	for _, id := range found {
+		results[s.keys[id]] = struct{}{}
+	}
+
+	return results
+}
+
+func (s *compactStore[T]) Clone() Store[T] {
+	return &compactStore[T]{
+		ids:          copyMap(s.ids),
+		keys:         slices.Clone(s.keys),
+		live:         slices.Clone(s.live),
+		dependencies: cloneCompactEdges(s.dependencies),
+		dependents:   cloneCompactEdges(s.dependents),
+	}
+}
+
+// Compact renumbers nodes to dense IDs, dropping IDs of deleted nodes,
+// and reallocates all slices to their exact sizes
+func (s *compactStore[T]) Compact() {
+	remap := make([]uint32, len(s.keys))
+	keys := make([]T, 0, len(s.ids))
+
+	for id := range s.keys {
+		if s.live.has(uint32(id)) {
+			remap[id] = uint32(len(keys))
+			keys = append(keys, s.keys[id])
+		}
+	}
+
+	// IDs keep their relative order, so remapped edges stay sorted
+	compact := func(old [][]compactEdge) [][]compactEdge {
+		edges := make([][]compactEdge, len(keys))
+		for id := range old {
+			if !s.live.has(uint32(id)) || len(old[id]) == 0 {
+				continue
+			}
+
+			remapped := make([]compactEdge, len(old[id]))
+			for i, edge := range old[id] {
+				remapped[i] = compactEdge{id: remap[edge.id], kind: edge.kind}
+			}
+
+			edges[remap[id]] = remapped
+		}
+
+		return edges
+	}
+
+	s.dependencies = compact(s.dependencies)
+	s.dependents = compact(s.dependents)
+	s.keys = keys
+	s.ids = make(map[T]uint32, len(keys))
+	s.live = make(bitset, (len(keys)+63)/64)
+
+	for id, node := range keys {
+		s.ids[node] = uint32(id)
+		s.live.set(uint32(id))
+	}
+}
+
+func (s *compactStore[T]) Validate() []DanglingReference[T] {
+	var refs []DanglingReference[T]
+
+	for node, id := range s.ids {
+		if int(id) >= len(s.keys) || !s.live.has(id) || s.keys[id] != node {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "ids", Key: node})
+		}
+	}
+
+	check := func(name string, edges, inverse [][]compactEdge) {
+		for id := range edges {
+			for _, edge := range edges[id] {
+				key, node := s.keys[id], s.keys[edge.id]
+				if !s.live.has(uint32(id)) {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: name, Key: key})
+					continue
+				}
+
+				if !s.live.has(edge.id) {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingNode, Edges: name, Key: key, Node: node})
+					continue
+				}
+
+				if edge.kind == 0 {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingKind, Edges: name, Key: key, Node: node})
+				}
+
+				i, found := searchCompactEdge(inverse[edge.id], uint32(id))
+				if !found || inverse[edge.id][i].kind != edge.kind {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingInverse, Edges: name, Key: key, Node: node})
+				}
+			}
+		}
+	}
+
+	check("dependencies", s.dependencies, s.dependents)
+	check("dependents", s.dependents, s.dependencies)
+
+	return refs
+}
+
+func searchCompactEdge(edges []compactEdge, id uint32) (int, bool) {
+	return slices.BinarySearchFunc(edges, id, func(edge compactEdge, id uint32) int {
+		return int(edge.id) - int(id)
+	})
+}
+
+func setCompactEdge(edges []compactEdge, id uint32, kind EdgeKind) []compactEdge {
+	i, found := searchCompactEdge(edges, id)
+	if found {
+		edges[i].kind = kind
+		return edges
+	}
+
+	return slices.Insert(edges, i, compactEdge{id: id, kind: kind})
+}
+
+func removeCompactEdge(edges []compactEdge, id uint32) []compactEdge {
+	i, found := searchCompactEdge(edges, id)
+	if !found {
+		return edges
+	}
+
+	edges = slices.Delete(edges, i, i+1)
+	if len(edges) == 0 {
+		return nil
+	}
+
+	return edges
+}
+
+func cloneCompactEdges(edges [][]compactEdge) [][]compactEdge {
+	cloned := make([][]compactEdge, len(edges))
+	for i := range edges {
+		cloned[i] = slices.Clone(edges[i])
+	}
+
+	return cloned
+}
+
+func (b bitset) has(i uint32) bool {
+	word := int(i / 64)
+	return word < len(b) && b[word]&(1<<(i%64)) != 0
+}
+
+func (b *bitset) set(i uint32) {
+	word := int(i / 64)
+	for word >= len(*b) {
+		*b = append(*b, 0)
+	}
+
+	(*b)[word] |= 1 << (i % 64)
+}
+
+func (b bitset) clear(i uint32) {
+	if word := int(i / 64); word < len(b) {
+		b[word] &^= 1 << (i % 64)
+	}
+}
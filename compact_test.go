@@ -0,0 +1,117 @@
+package soydepend_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func assertSameGraph[T comparable](t *testing.T, expected, actual *soydepend.Graph[T]) {
+	t.Helper()
+
+	expected.AssertRelationships()
+	actual.AssertRelationships()
+
+	if !reflect.DeepEqual(expected.GraphNodes(), actual.GraphNodes()) {
+		t.Fatalf("unexpected nodes: expecting %v, got %v", expected.GraphNodes(), actual.GraphNodes())
+	}
+
+	if !reflect.DeepEqual(expected.GraphDependencies(), actual.GraphDependencies()) {
+		t.Fatalf("unexpected dependencies: expecting %v, got %v", expected.GraphDependencies(), actual.GraphDependencies())
+	}
+
+	if !reflect.DeepEqual(expected.GraphDependents(), actual.GraphDependents()) {
+		t.Fatalf("unexpected dependents: expecting %v, got %v", expected.GraphDependents(), actual.GraphDependents())
+	}
+
+	if !reflect.DeepEqual(expected.Layers(soydepend.KindBuild), actual.Layers(soydepend.KindBuild)) {
+		t.Fatalf("unexpected layers: expecting %v, got %v", expected.Layers(soydepend.KindBuild), actual.Layers(soydepend.KindBuild))
+	}
+
+	for dependent, dependencies := range expected.GraphDependencies() {
+		for dependency := range dependencies {
+			if expected.EdgeKindOf(dependent, dependency) != actual.EdgeKindOf(dependent, dependency) {
+				t.Fatalf("unexpected kind of %v -> %v", dependent, dependency)
+			}
+		}
+	}
+
+	for node := range expected.GraphNodes() {
+		if !reflect.DeepEqual(expected.Dependencies(node), actual.Dependencies(node)) {
+			t.Fatalf("unexpected dependencies of %v", node)
+		}
+
+		if !reflect.DeepEqual(expected.Dependents(node, soydepend.KindRuntime), actual.Dependents(node, soydepend.KindRuntime)) {
+			t.Fatalf("unexpected runtime dependents of %v", node)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	expected := initTestGraph(t)
+	g := soydepend.NewCompact[string]()
+
+	for dependent, dependencies := range expected.GraphDependencies() {
+		for dependency := range dependencies {
+			if err := g.Depend(dependent, dependency); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+		}
+	}
+
+	assertSameGraph(t, &expected, &g)
+
+	for _, graph := range []*soydepend.Graph[string]{&expected, &g} {
+		graph.RemoveAutoRemove("d")
+		graph.AddNode("z")
+		graph.Realloc()
+	}
+
+	assertSameGraph(t, &expected, &g)
+
+	err := g.Depend("a", "d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.Depend("d", "b")
+	if err == nil {
+		t.Fatal("expecting circular dependency error")
+	}
+}
+
+func TestCompactRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	expected, g := soydepend.New[int](), soydepend.NewCompact[int]()
+
+	for i := 0; i < 5000; i++ {
+		op := rng.Intn(20)
+		dependent, dependency := rng.Intn(100), rng.Intn(100)
+		kind := soydepend.EdgeKind(1 << rng.Intn(4))
+
+		var errExpected, err error
+		switch {
+		case op < 12:
+			errExpected, err = expected.Depend(dependent, dependency, kind), g.Depend(dependent, dependency, kind)
+		case op < 17:
+			errExpected, err = expected.Undepend(dependent, dependency, kind), g.Undepend(dependent, dependency, kind)
+		case op < 19:
+			expected.Delete(dependent)
+			g.Delete(dependent)
+		default:
+			g.Realloc()
+		}
+
+		if (errExpected == nil) != (err == nil) {
+			t.Fatalf("op %d: expecting error %v, got %v", i, errExpected, err)
+		}
+
+		if i%500 == 0 {
+			assertSameGraph(t, &expected, &g)
+		}
+	}
+
+	assertSameGraph(t, &expected, &g)
+}
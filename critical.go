@@ -0,0 +1,109 @@
+package soydepend
+
+// Schedule is the timing of a node computed by CriticalPath.
+// A node is on the critical path if its Slack is 0.
+type Schedule struct {
+	EarliestStart  float64
+	EarliestFinish float64
+	LatestStart    float64
+	LatestFinish   float64
+	Slack          float64 // How much the node can be delayed without delaying the whole graph
+}
+
+// CriticalPath is the heaviest path through graph, computed by Graph.CriticalPath
+type CriticalPath[T comparable] struct {
+	Path      []T            // Nodes on the critical path, from the first node to run to the last
+	Cost      float64        // Total cost of the critical path, i.e. the earliest finish of the whole graph
+	Schedules map[T]Schedule // Schedules of all nodes in graph
+}
+
+// CriticalPath computes the heaviest path through g, as well as
+// earliest/latest start times and slack of every node.
+//
+// duration returns how long a node takes to run, and weight returns the delay
+// between dependency finishing and dependent starting. A nil duration gives
+// every node a duration of 1, so that the critical path is the longest chain
+// of nodes, and a nil weight gives every edge a weight of 0.
+//
+// Ties between equally heavy paths are broken by the topological order
+// maintained by g, so the result is stable for graphs built by the same calls.
+func (g *Graph[T]) CriticalPath(
+	duration func(node T) float64,
+	weight func(dependent, dependency T) float64,
+) CriticalPath[T] {
+	if duration == nil {
+		duration = func(T) float64 { return 1 }
+	}
+
+	if weight == nil {
+		weight = func(T, T) float64 { return 0 }
+	}
+
+	order := g.nodeSet().SliceFunc(g.byOrder)
+
+	schedules := make(map[T]Schedule, len(order))
+	predecessors := make(map[T]T, len(order))
+
+	var sink T
+	var cost float64
+
+	// Forward pass, dependencies before dependents
+	for i, node := range order {
+		var start float64
+		g.store.RangeDependencies(node, func(dependency T, _ EdgeKind) bool {
+			ready := schedules[dependency].EarliestFinish + weight(node, dependency)
+			predecessor, ok := predecessors[node]
+			if !ok || ready > start || (ready == start && g.order[dependency] < g.order[predecessor]) {
+				start = ready
+				predecessors[node] = dependency
+			}
+
+			return true
+		})
+
+		finish := start + duration(node)
+		schedules[node] = Schedule{EarliestStart: start, EarliestFinish: finish}
+
+		if i == 0 || finish > cost {
+			cost = finish
+			sink = node
+		}
+	}
+
+	// Backward pass, dependents before dependencies
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		finish := cost
+		g.store.RangeDependents(node, func(dependent T, _ EdgeKind) bool {
+			if latest := schedules[dependent].LatestStart - weight(dependent, node); latest < finish {
+				finish = latest
+			}
+
+			return true
+		})
+
+		schedule := schedules[node]
+		schedule.LatestFinish = finish
+		schedule.LatestStart = finish - duration(node)
+		schedule.Slack = schedule.LatestStart - schedule.EarliestStart
+		schedules[node] = schedule
+	}
+
+	var path []T
+	if len(order) != 0 {
+		path = []T{sink}
+		for node, ok := predecessors[sink]; ok; node, ok = predecessors[node] {
+			path = append(path, node)
+		}
+
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+	}
+
+	return CriticalPath[T]{
+		Path:      path,
+		Cost:      cost,
+		Schedules: schedules,
+	}
+}
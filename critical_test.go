@@ -0,0 +1,104 @@
+package soydepend_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestCriticalPath(t *testing.T) {
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, map[string][]string{
+		"b": {"a"},
+		"c": {"a"},
+		"d": {"b", "c"},
+	})
+
+	g.AddNode("x")
+
+	durations := map[string]float64{"a": 3, "b": 2, "c": 4, "d": 1, "x": 2}
+	duration := func(node string) float64 { return durations[node] }
+
+	cp := g.CriticalPath(duration, nil)
+	assertCriticalPath(t, cp, []string{"a", "c", "d"}, 8)
+	assertSchedule(t, cp, "a", soydepend.Schedule{EarliestStart: 0, EarliestFinish: 3, LatestStart: 0, LatestFinish: 3, Slack: 0})
+	assertSchedule(t, cp, "b", soydepend.Schedule{EarliestStart: 3, EarliestFinish: 5, LatestStart: 5, LatestFinish: 7, Slack: 2})
+	assertSchedule(t, cp, "c", soydepend.Schedule{EarliestStart: 3, EarliestFinish: 7, LatestStart: 3, LatestFinish: 7, Slack: 0})
+	assertSchedule(t, cp, "d", soydepend.Schedule{EarliestStart: 7, EarliestFinish: 8, LatestStart: 7, LatestFinish: 8, Slack: 0})
+	assertSchedule(t, cp, "x", soydepend.Schedule{EarliestStart: 0, EarliestFinish: 2, LatestStart: 6, LatestFinish: 8, Slack: 6})
+
+	// Delay between b finishing and d starting makes a -> b -> d critical
+	weight := func(dependent, dependency string) float64 {
+		if dependent == "d" && dependency == "b" {
+			return 3
+		}
+
+		return 0
+	}
+
+	cp = g.CriticalPath(duration, weight)
+	assertCriticalPath(t, cp, []string{"a", "b", "d"}, 9)
+	assertSchedule(t, cp, "c", soydepend.Schedule{EarliestStart: 3, EarliestFinish: 7, LatestStart: 4, LatestFinish: 8, Slack: 1})
+	assertSchedule(t, cp, "d", soydepend.Schedule{EarliestStart: 8, EarliestFinish: 9, LatestStart: 8, LatestFinish: 9, Slack: 0})
+
+	// Unit durations give the longest chain
+	g.Undepend("d", "c")
+	cp = g.CriticalPath(nil, nil)
+	assertCriticalPath(t, cp, []string{"a", "b", "d"}, 3)
+
+	empty := soydepend.New[string]()
+	cp = empty.CriticalPath(nil, nil)
+	if len(cp.Path) != 0 || cp.Cost != 0 || len(cp.Schedules) != 0 {
+		t.Fatalf("unexpected critical path for empty graph: %+v", cp)
+	}
+}
+
+func TestCriticalPathTie(t *testing.T) {
+	build := func() soydepend.Graph[string] {
+		g := soydepend.New[string]()
+		for _, top := range []string{"p", "q", "r", "s"} {
+			for _, middle := range []string{"b", "c", "d", "e"} {
+				if err := g.Depend(top, middle); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if err := g.Depend(middle, "a"); err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+			}
+		}
+
+		return g
+	}
+
+	// Every path through the graph is equally heavy
+	g := build()
+	expected := g.CriticalPath(nil, nil)
+	if len(expected.Path) != 3 || expected.Cost != 3 {
+		t.Fatalf("unexpected critical path %v", expected.Path)
+	}
+
+	for i := 0; i < 50; i++ {
+		other := build()
+		for _, graph := range []*soydepend.Graph[string]{&g, &other} {
+			assertCriticalPath(t, graph.CriticalPath(nil, nil), expected.Path, expected.Cost)
+		}
+	}
+}
+
+func assertCriticalPath(t *testing.T, cp soydepend.CriticalPath[string], path []string, cost float64) {
+	if !reflect.DeepEqual(cp.Path, path) {
+		t.Fatalf("unexpected critical path: expecting %v, got %v", path, cp.Path)
+	}
+
+	if cp.Cost != cost {
+		t.Fatalf("unexpected critical path cost: expecting %v, got %v", cost, cp.Cost)
+	}
+}
+
+func assertSchedule(t *testing.T, cp soydepend.CriticalPath[string], node string, expected soydepend.Schedule) {
+	if actual := cp.Schedules[node]; actual != expected {
+		t.Fatalf("unexpected schedule for %s: expecting %+v, got %+v", node, expected, actual)
+	}
+}
@@ -0,0 +1,276 @@
+package soydepend
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Closure selects a deep closure of a node
+type Closure uint8
+
+const (
+	ClosureNone         Closure = iota
+	ClosureDependencies         // Deep dependencies, like Dependencies
+	ClosureDependents           // Deep dependents, like Dependents
+)
+
+// DOTOptions configures WriteDOT. The zero value writes every node labeled with fmt.Sprint.
+type DOTOptions[T comparable] struct {
+	Name  string     // Name of the digraph, omitted if empty
+	Kinds []EdgeKind // Only write edges with any of the kinds, and use them for RankLayers and Highlight
+
+	Label     func(node T) string                                            // Label of node, fmt.Sprint by default
+	NodeAttrs func(node T) map[string]string                                 // Extra attributes of node, overriding defaults
+	EdgeAttrs func(dependent, dependency T, kind EdgeKind) map[string]string // Extra attributes of edge, overriding defaults
+
+	RankLayers bool // Put nodes of each layer from Layers in the same rank
+
+	Highlight        T       // Node whose closure is highlighted
+	HighlightClosure Closure // Closure of Highlight to highlight, or ClosureNone to highlight nothing
+}
+
+// highlightColor is the color of highlighted nodes and edges
+const highlightColor = "red"
+
+// WriteDOT writes g to w as a Graphviz DOT digraph, with edges pointing
+// from dependents to dependencies. Nodes are written in topological order,
+// sorted by label within each layer, so the output is stable.
+//
+// Nodes are identified by their position in the output, e.g. n0, n1,
+// so that nodes with equal labels are kept apart.
+//
+// By default, edges with kinds other than just KindRuntime are labeled with their kinds,
+// and the highlighted node and its closure are colored red, along with edges between them.
+//
+// It returns ErrInvalidEdgeKind if any of Kinds is 0 or not within KindAll.
+func (g *Graph[T]) WriteDOT(w io.Writer, opts DOTOptions[T]) error {
+	err := checkKinds(opts.Kinds)
+	if err != nil {
+		return err
+	}
+
+	label := opts.Label
+	if label == nil {
+		label = func(node T) string { return fmt.Sprint(node) }
+	}
+
+	layers := g.sortedLayers(label, opts.Kinds...)
+	mask := kindMask(opts.Kinds, KindAll)
+
+	ids := make(map[T]int, g.store.Len())
+	for _, layer := range layers {
+		for _, node := range layer {
+			ids[node] = len(ids)
+		}
+	}
+
+	highlighted := g.highlightSet(opts.Highlight, opts.HighlightClosure, opts.Kinds...)
+
+	bw := bufio.NewWriter(w)
+	if opts.Name != "" {
+		fmt.Fprintf(bw, "digraph %s {\n", quoteDOT(opts.Name))
+	} else {
+		fmt.Fprint(bw, "digraph {\n")
+	}
+
+	for _, layer := range layers {
+		for _, node := range layer {
+			attrs := map[string]string{"label": label(node)}
+			if highlighted.Contains(node) {
+				attrs["color"] = highlightColor
+				attrs["fontcolor"] = highlightColor
+			}
+
+			if opts.HighlightClosure != ClosureNone && node == opts.Highlight {
+				attrs["penwidth"] = "2"
+			}
+
+			if opts.NodeAttrs != nil {
+				mergeAttrs(attrs, opts.NodeAttrs(node))
+			}
+
+			fmt.Fprintf(bw, "\tn%d%s;\n", ids[node], formatAttrs(attrs))
+		}
+	}
+
+	for _, layer := range layers {
+		for _, dependent := range layer {
+			for _, dependency := range g.sortedDependencies(dependent, ids) {
+				kind := g.store.EdgeKind(dependent, dependency)
+				if kind&mask == 0 {
+					continue
+				}
+
+				attrs := make(map[string]string)
+				if kind != KindRuntime {
+					attrs["label"] = kind.String()
+				}
+
+				if highlighted.Contains(dependent) && highlighted.Contains(dependency) {
+					attrs["color"] = highlightColor
+				}
+
+				if opts.EdgeAttrs != nil {
+					mergeAttrs(attrs, opts.EdgeAttrs(dependent, dependency, kind))
+				}
+
+				fmt.Fprintf(bw, "\tn%d -> n%d%s;\n", ids[dependent], ids[dependency], formatAttrs(attrs))
+			}
+		}
+	}
+
+	if opts.RankLayers {
+		for _, layer := range layers {
+			members := make([]string, len(layer))
+			for i, node := range layer {
+				members[i] = fmt.Sprintf("n%d;", ids[node])
+			}
+
+			fmt.Fprintf(bw, "\t{ rank=same; %s }\n", strings.Join(members, " "))
+		}
+	}
+
+	fmt.Fprint(bw, "}\n")
+
+	return bw.Flush()
+}
+
+// sortedLayers returns Layers with each layer sorted by label,
+// then by Go-syntax representation of nodes with equal labels
+func (g *Graph[T]) sortedLayers(label func(T) string, kinds ...EdgeKind) [][]T {
+	keys := make(map[T]string, g.store.Len())
+	byKey := func(a, b T) int { return strings.Compare(keys[a], keys[b]) }
+
+	var layers [][]T
+	for _, layer := range g.Layers(kinds...) {
+		for node := range layer {
+			keys[node] = label(node) + "\x00" + fmt.Sprintf("%#v", node)
+		}
+
+		layers = append(layers, layer.SliceFunc(byKey))
+	}
+
+	return layers
+}
+
+// sortedDependencies returns direct dependencies of node sorted by ids
+func (g *Graph[T]) sortedDependencies(node T, ids map[T]int) []T {
+	dependencies := adjacentSet(g.store.RangeDependencies, node).Slice()
+	slices.SortFunc(dependencies, func(a, b T) int { return cmp.Compare(ids[a], ids[b]) })
+
+	return dependencies
+}
+
+// highlightSet returns node and its closure, or nil if closure is ClosureNone or node is not in g
+func (g *Graph[T]) highlightSet(node T, closure Closure, kinds ...EdgeKind) Set[T] {
+	if closure == ClosureNone || !g.store.HasNode(node) {
+		return nil
+	}
+
+	var nodes Set[T]
+	switch closure {
+	case ClosureDependencies:
+		nodes = g.Dependencies(node, kinds...)
+	case ClosureDependents:
+		nodes = g.Dependents(node, kinds...)
+	}
+
+	if nodes == nil {
+		nodes = make(Set[T])
+	}
+
+	nodes[node] = struct{}{}
+
+	return nodes
+}
+
+func mergeAttrs(attrs, extra map[string]string) {
+	for key, value := range extra {
+		attrs[key] = value
+	}
+}
+
+// formatAttrs formats attrs as a DOT attribute list sorted by keys, e.g. ` [color="red", label="a"]`
+func formatAttrs(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = quoteDOT(key) + "=" + quoteDOT(attrs[key])
+	}
+
+	return " [" + strings.Join(pairs, ", ") + "]"
+}
+
+// quoteDOT quotes s as a DOT string, leaving plain IDs unquoted
+func quoteDOT(s string) string {
+	if isPlainDOT(s) {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
+
+// isPlainDOT returns whether s is a DOT ID that needs no quoting, i.e. a numeral,
+// or letters, digits and underscores not starting with a digit
+func isPlainDOT(s string) bool {
+	if isNumeralDOT(s) {
+		return true
+	}
+
+	if s == "" || isDigit(s[0]) || isKeywordDOT(s) {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !isDigit(c) && c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNumeralDOT returns whether s is a DOT numeral, e.g. 2, -1.5 or .5
+func isNumeralDOT(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	digits, dots := 0, 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case isDigit(s[i]):
+			digits++
+		case s[i] == '.':
+			dots++
+		default:
+			return false
+		}
+	}
+
+	return digits != 0 && dots <= 1
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isKeywordDOT(s string) bool {
+	switch strings.ToLower(s) {
+	case "node", "edge", "graph", "digraph", "subgraph", "strict":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package soydepend_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func dotTestGraph(t *testing.T) soydepend.Graph[string] {
+	g := soydepend.New[string]()
+	g.AddNode(`say "hi"`)
+
+	for _, edge := range []struct {
+		dependent, dependency string
+		kind                  soydepend.EdgeKind
+	}{
+		{dependent: "b", dependency: "a", kind: soydepend.KindRuntime},
+		{dependent: "c", dependency: "a", kind: soydepend.KindBuild | soydepend.KindTest},
+		{dependent: "c", dependency: "b", kind: soydepend.KindRuntime},
+		{dependent: "d", dependency: "c", kind: soydepend.KindRuntime},
+	} {
+		if err := g.Depend(edge.dependent, edge.dependency, edge.kind); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return g
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := dotTestGraph(t)
+
+	var b strings.Builder
+	err := g.WriteDOT(&b, soydepend.DOTOptions[string]{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `digraph {
+	n0 [label=a];
+	n1 [label="say \"hi\""];
+	n2 [label=b];
+	n3 [label=c];
+	n4 [label=d];
+	n2 -> n0;
+	n3 -> n0 [label="build|test"];
+	n3 -> n2;
+	n4 -> n3;
+}
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+}
+
+func TestWriteDOTOptions(t *testing.T) {
+	g := dotTestGraph(t)
+
+	var b strings.Builder
+	err := g.WriteDOT(&b, soydepend.DOTOptions[string]{
+		Name:  "deps graph",
+		Kinds: []soydepend.EdgeKind{soydepend.KindRuntime},
+		Label: strings.ToUpper,
+		NodeAttrs: func(node string) map[string]string {
+			if node == "d" {
+				return map[string]string{"shape": "box"}
+			}
+
+			return nil
+		},
+		EdgeAttrs: func(dependent, _ string, _ soydepend.EdgeKind) map[string]string {
+			return map[string]string{"tooltip": "from " + dependent}
+		},
+		RankLayers:       true,
+		Highlight:        "b",
+		HighlightClosure: soydepend.ClosureDependents,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// Only runtime edges are written, so the build edge c->a is left out, and c still ranks above b through c->b
+	expected := `digraph "deps graph" {
+	n0 [label=A];
+	n1 [label="SAY \"HI\""];
+	n2 [color=red, fontcolor=red, label=B, penwidth=2];
+	n3 [color=red, fontcolor=red, label=C];
+	n4 [color=red, fontcolor=red, label=D, shape=box];
+	n2 -> n0 [tooltip="from b"];
+	n3 -> n2 [color=red, tooltip="from c"];
+	n4 -> n3 [color=red, tooltip="from d"];
+	{ rank=same; n0; n1; }
+	{ rank=same; n2; }
+	{ rank=same; n3; }
+	{ rank=same; n4; }
+}
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+}
@@ -0,0 +1,621 @@
+package soydepend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrDOTSyntax = errors.New("DOT syntax error")
+
+// DOTDirection tells how ParseDOT maps DOT edges to dependencies
+type DOTDirection uint8
+
+const (
+	DependentToDependency DOTDirection = iota // a -> b means a depends on b, like WriteDOT
+	DependencyToDependent                     // a -> b means b depends on a, like build order graphs
+)
+
+// ParseDOTOptions configures ParseDOT
+type ParseDOTOptions struct {
+	Direction DOTDirection
+	UseLabels bool // Name nodes by their label attributes instead of their DOT IDs, e.g. to read WriteDOT output
+	EdgeKinds bool // Parse edge labels as edge kinds like in WriteDOT output, edges without labels are KindRuntime
+}
+
+// DOTError is an error at a line of DOT input.
+// It wraps ErrDOTSyntax for syntax errors, or *EdgeError[string] for rejected edges.
+type DOTError struct {
+	Line int
+	Err  error
+}
+
+func (e *DOTError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *DOTError) Unwrap() error {
+	return e.Err
+}
+
+// ParseDOT reads a DOT digraph from r into a new graph, adding nodes in order
+// of appearance and then edges with Depend. Nodes in subgraphs are added like
+// any other nodes, and edges to or from subgraphs connect every node in them.
+// Attributes are ignored, except labels when set in opts.
+//
+// Syntax errors and edges rejected by Depend, e.g. cycles,
+// are returned as *DOTError with the line of the offending statement.
+func ParseDOT(r io.Reader, opts ParseDOTOptions) (Graph[string], error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return Graph[string]{}, err
+	}
+
+	tokens, err := lexDOT(string(input))
+	if err != nil {
+		return Graph[string]{}, err
+	}
+
+	p := &dotParser{tokens: tokens, attrs: make(map[string]map[string]string)}
+	err = p.parseGraph()
+	if err != nil {
+		return Graph[string]{}, err
+	}
+
+	name := func(id string) string {
+		if label := p.attrs[id]["label"]; opts.UseLabels && label != "" {
+			return label
+		}
+
+		return id
+	}
+
+	g := New[string]()
+	for _, id := range p.nodes {
+		g.AddNode(name(id))
+	}
+
+	for _, e := range p.edges {
+		edge := Edge[string]{Dependent: name(e.from), Dependency: name(e.to)}
+		if opts.Direction == DependencyToDependent {
+			edge.Dependent, edge.Dependency = edge.Dependency, edge.Dependent
+		}
+
+		var kinds []EdgeKind
+		if label := e.attrs["label"]; opts.EdgeKinds && label != "" {
+			kind, err := parseEdgeKind(label)
+			if err != nil {
+				return Graph[string]{}, &DOTError{Line: e.line, Err: &EdgeError[string]{Edge: edge, Err: err}}
+			}
+
+			kinds = append(kinds, kind)
+		}
+
+		err = g.Depend(edge.Dependent, edge.Dependency, kinds...)
+		if err != nil {
+			return Graph[string]{}, &DOTError{Line: e.line, Err: &EdgeError[string]{Edge: edge, Err: err}}
+		}
+	}
+
+	return g, nil
+}
+
+type dotTokenKind uint8
+
+const (
+	dotEOF   dotTokenKind = iota
+	dotID                 // Identifier, numeral, quoted or HTML string
+	dotPunct              // One of { } [ ] = ; , : + -> --
+)
+
+type dotToken struct {
+	kind   dotTokenKind
+	text   string
+	quoted bool // Whether an ID is a quoted or HTML string, which is never a keyword
+	line   int
+}
+
+func dotSyntaxError(line int, format string, args ...any) error {
+	return &DOTError{Line: line, Err: fmt.Errorf("%w: %s", ErrDOTSyntax, fmt.Sprintf(format, args...))}
+}
+
+// lexDOT splits input into tokens, dropping whitespace, comments and lines starting with #
+func lexDOT(input string) ([]dotToken, error) {
+	var tokens []dotToken
+
+	line, lineStart := 1, true
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == '\n':
+			line++
+			lineStart = true
+			i++
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+
+		case c == '#' && lineStart:
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+
+			continue
+
+		case strings.HasPrefix(input[i:], "//"):
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+
+			continue
+
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end == -1 {
+				return nil, dotSyntaxError(line, "unterminated comment")
+			}
+
+			line += strings.Count(input[i:i+2+end], "\n")
+			i += 2 + end + 2
+			continue
+		}
+
+		lineStart = false
+		start := line
+
+		switch {
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(input) && input[j] != '"'; j++ {
+				if input[j] == '\n' {
+					line++
+				}
+
+				if input[j] != '\\' || j+1 == len(input) {
+					b.WriteByte(input[j])
+					continue
+				}
+
+				// Backslash escapes the next character like in Graphviz,
+				// and only escaped quotes and backslashes are unescaped
+				j++
+				switch input[j] {
+				case '"', '\\':
+					b.WriteByte(input[j])
+				case '\n':
+					line++ // Line continuation
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(input[j])
+				}
+			}
+
+			if j == len(input) {
+				return nil, dotSyntaxError(start, "unterminated string")
+			}
+
+			tokens = append(tokens, dotToken{kind: dotID, text: b.String(), quoted: true, line: start})
+			i = j + 1
+
+		case c == '<':
+			depth, j := 0, i
+			for ; j < len(input); j++ {
+				switch input[j] {
+				case '<':
+					depth++
+				case '>':
+					depth--
+				case '\n':
+					line++
+				}
+
+				if depth == 0 {
+					break
+				}
+			}
+
+			if j == len(input) {
+				return nil, dotSyntaxError(start, "unterminated HTML string")
+			}
+
+			tokens = append(tokens, dotToken{kind: dotID, text: input[i+1 : j], quoted: true, line: start})
+			i = j + 1
+
+		case strings.HasPrefix(input[i:], "->") || strings.HasPrefix(input[i:], "--"):
+			tokens = append(tokens, dotToken{kind: dotPunct, text: input[i : i+2], line: start})
+			i += 2
+
+		case strings.IndexByte("{}[]=;,:+", c) != -1:
+			tokens = append(tokens, dotToken{kind: dotPunct, text: string(c), line: start})
+			i++
+
+		case c == '-' || c == '.' || isDigit(c):
+			j := i + 1
+			for j < len(input) && (input[j] == '.' || isDigit(input[j])) {
+				j++
+			}
+
+			if !isNumeralDOT(input[i:j]) {
+				return nil, dotSyntaxError(start, "invalid numeral %q", input[i:j])
+			}
+
+			tokens = append(tokens, dotToken{kind: dotID, text: input[i:j], line: start})
+			i = j
+
+		case isIDStartDOT(c):
+			j := i + 1
+			for j < len(input) && (isIDStartDOT(input[j]) || isDigit(input[j])) {
+				j++
+			}
+
+			tokens = append(tokens, dotToken{kind: dotID, text: input[i:j], line: start})
+			i = j
+
+		default:
+			return nil, dotSyntaxError(start, "unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, dotToken{kind: dotEOF, line: line}), nil
+}
+
+func isIDStartDOT(c byte) bool {
+	return c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+type dotEdge struct {
+	from, to string
+	attrs    map[string]string
+	line     int
+}
+
+// dotScope holds default node and edge attributes of a graph or subgraph
+type dotScope struct {
+	node, edge map[string]string
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+
+	nodes []string                     // Node IDs in order of appearance
+	attrs map[string]map[string]string // Attributes of nodes
+	edges []dotEdge
+}
+
+func (p *dotParser) peek() dotToken { return p.tokens[p.pos] }
+
+func (p *dotParser) next() dotToken {
+	token := p.tokens[p.pos]
+	if token.kind != dotEOF {
+		p.pos++
+	}
+
+	return token
+}
+
+// is returns whether the next token is punctuation text
+func (p *dotParser) is(text string) bool {
+	token := p.peek()
+	return token.kind == dotPunct && token.text == text
+}
+
+// isKeyword returns whether the next token is keyword, which is case-insensitive
+func (p *dotParser) isKeyword(keyword string) bool {
+	token := p.peek()
+	return token.kind == dotID && !token.quoted && strings.EqualFold(token.text, keyword)
+}
+
+func (p *dotParser) expect(text string) error {
+	if !p.is(text) {
+		return p.unexpected(fmt.Sprintf("%q", text))
+	}
+
+	p.next()
+
+	return nil
+}
+
+func (p *dotParser) unexpected(expecting string) error {
+	token := p.peek()
+	if token.kind == dotEOF {
+		return dotSyntaxError(token.line, "unexpected end of input, expecting %s", expecting)
+	}
+
+	return dotSyntaxError(token.line, "unexpected %q, expecting %s", token.text, expecting)
+}
+
+// graph : [ strict ] digraph [ ID ] '{' stmt_list '}'
+func (p *dotParser) parseGraph() error {
+	if p.isKeyword("strict") {
+		p.next()
+	}
+
+	if p.isKeyword("graph") {
+		return dotSyntaxError(p.peek().line, "undirected graph, expecting digraph")
+	}
+
+	if !p.isKeyword("digraph") {
+		return p.unexpected("digraph")
+	}
+
+	p.next()
+	if p.peek().kind == dotID && !p.isKeyword("subgraph") {
+		_, err := p.parseID()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := p.expect("{")
+	if err != nil {
+		return err
+	}
+
+	_, err = p.parseStmtList(dotScope{node: map[string]string{}, edge: map[string]string{}})
+	if err != nil {
+		return err
+	}
+
+	err = p.expect("}")
+	if err != nil {
+		return err
+	}
+
+	if p.peek().kind != dotEOF {
+		return p.unexpected("end of input")
+	}
+
+	return nil
+}
+
+// parseStmtList parses statements until '}', returning nodes appearing in them
+func (p *dotParser) parseStmtList(scope dotScope) ([]string, error) {
+	var members []string
+	for !p.is("}") {
+		if p.peek().kind == dotEOF {
+			return nil, p.unexpected(`"}"`)
+		}
+
+		nodes, err := p.parseStmt(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, nodes...)
+		if p.is(";") {
+			p.next()
+		}
+	}
+
+	return members, nil
+}
+
+func (p *dotParser) parseStmt(scope dotScope) ([]string, error) {
+	switch {
+	case p.isKeyword("graph"), p.isKeyword("node"), p.isKeyword("edge"):
+		keyword := strings.ToLower(p.next().text)
+		attrs, err := p.parseAttrLists(true)
+		if err != nil {
+			return nil, err
+		}
+
+		switch keyword {
+		case "node":
+			mergeAttrs(scope.node, attrs)
+		case "edge":
+			mergeAttrs(scope.edge, attrs)
+		}
+
+		return nil, nil
+
+	case p.is("{"), p.isKeyword("subgraph"):
+		return p.parseEdgeStmt(scope)
+
+	case p.peek().kind == dotID:
+		if p.tokens[p.pos+1].kind == dotPunct && p.tokens[p.pos+1].text == "=" {
+			// Graph attribute ID = ID
+			p.next()
+			p.next()
+			_, err := p.parseID()
+			return nil, err
+		}
+
+		return p.parseEdgeStmt(scope)
+	}
+
+	return nil, p.unexpected("statement")
+}
+
+// parseEdgeStmt parses a node statement, or an edge statement if edge operators follow
+func (p *dotParser) parseEdgeStmt(scope dotScope) ([]string, error) {
+	line := p.peek().line
+
+	operand, err := p.parseOperand(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	operands := [][]string{operand}
+	for p.is("->") || p.is("--") {
+		if p.next().text == "--" {
+			return nil, dotSyntaxError(line, `undirected edge "--" in digraph`)
+		}
+
+		operand, err = p.parseOperand(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, operand)
+	}
+
+	attrs, err := p.parseAttrLists(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	for _, operand := range operands {
+		members = append(members, operand...)
+	}
+
+	// Node statement
+	if len(operands) == 1 {
+		for _, node := range members {
+			mergeAttrs(p.attrs[node], attrs)
+		}
+
+		return members, nil
+	}
+
+	edgeAttrs := make(map[string]string)
+	mergeAttrs(edgeAttrs, scope.edge)
+	mergeAttrs(edgeAttrs, attrs)
+
+	for i := 1; i < len(operands); i++ {
+		for _, from := range operands[i-1] {
+			for _, to := range operands[i] {
+				p.edges = append(p.edges, dotEdge{from: from, to: to, attrs: edgeAttrs, line: line})
+			}
+		}
+	}
+
+	return members, nil
+}
+
+// parseOperand parses a node ID with an optional port, or a subgraph, returning its nodes
+func (p *dotParser) parseOperand(scope dotScope) ([]string, error) {
+	if p.is("{") || p.isKeyword("subgraph") {
+		return p.parseSubgraph(scope)
+	}
+
+	id, err := p.parseID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ports are ignored
+	for i := 0; i < 2 && p.is(":"); i++ {
+		p.next()
+		_, err = p.parseID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.addNode(id, scope)
+
+	return []string{id}, nil
+}
+
+// subgraph : [ subgraph [ ID ] ] '{' stmt_list '}'
+func (p *dotParser) parseSubgraph(scope dotScope) ([]string, error) {
+	if p.isKeyword("subgraph") {
+		p.next()
+		if p.peek().kind == dotID {
+			_, err := p.parseID()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	// Defaults set in subgraph only apply within subgraph
+	inner := dotScope{node: make(map[string]string), edge: make(map[string]string)}
+	mergeAttrs(inner.node, scope.node)
+	mergeAttrs(inner.edge, scope.edge)
+
+	members, err := p.parseStmtList(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, p.expect("}")
+}
+
+// parseAttrLists parses attribute lists like [a=b, c=d][e=f], which are required if required is true
+func (p *dotParser) parseAttrLists(required bool) (map[string]string, error) {
+	if required && !p.is("[") {
+		return nil, p.unexpected(`"["`)
+	}
+
+	attrs := make(map[string]string)
+	for p.is("[") {
+		p.next()
+
+		for !p.is("]") {
+			key, err := p.parseID()
+			if err != nil {
+				return nil, err
+			}
+
+			err = p.expect("=")
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := p.parseID()
+			if err != nil {
+				return nil, err
+			}
+
+			attrs[key] = value
+			if p.is(",") || p.is(";") {
+				p.next()
+			}
+		}
+
+		p.next()
+	}
+
+	return attrs, nil
+}
+
+// parseID parses an ID, concatenating quoted strings joined by '+'
+func (p *dotParser) parseID() (string, error) {
+	token := p.peek()
+	if token.kind != dotID {
+		return "", p.unexpected("ID")
+	}
+
+	p.next()
+	id := token.text
+
+	for token.quoted && p.is("+") {
+		p.next()
+
+		token = p.peek()
+		if token.kind != dotID || !token.quoted {
+			return "", p.unexpected("quoted string")
+		}
+
+		p.next()
+		id += token.text
+	}
+
+	return id, nil
+}
+
+// addNode adds node with default attributes of scope, if it has not appeared yet
+func (p *dotParser) addNode(node string, scope dotScope) {
+	if _, ok := p.attrs[node]; ok {
+		return
+	}
+
+	attrs := make(map[string]string)
+	mergeAttrs(attrs, scope.node)
+
+	p.nodes = append(p.nodes, node)
+	p.attrs[node] = attrs
+}
@@ -0,0 +1,142 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestParseDOT(t *testing.T) {
+	input := `# preprocessor line
+strict digraph "deps" {
+	// Defaults and graph attributes are accepted
+	graph [rankdir=LR]; node [shape=box]
+	label = "deps"
+
+	a; isolated [color=red]
+	"b c" -> a
+	d -> "b" + " c" -> a [label="build|test"]
+	/* multi-line
+	   comment */
+	e:port:n -> { d f }
+	subgraph cluster_x {
+		edge [style=dashed]
+		x -> y
+		<html <b>id</b>>
+	}
+	-1.5 -> x
+}
+`
+
+	g, err := soydepend.ParseDOT(strings.NewReader(input), soydepend.ParseDOTOptions{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+
+	expectedNodes := soydepend.NodeSet("a", "isolated", "b c", "d", "e", "f", "x", "y", "html <b>id</b>", "-1.5")
+	if !reflect.DeepEqual(g.GraphNodes(), expectedNodes) {
+		t.Fatalf("unexpected nodes %v", g.GraphNodes())
+	}
+
+	expectedEdges := soydepend.Edges[string]{
+		"b c":  soydepend.NodeSet("a"),
+		"d":    soydepend.NodeSet("b c"),
+		"e":    soydepend.NodeSet("d", "f"),
+		"x":    soydepend.NodeSet("y"),
+		"-1.5": soydepend.NodeSet("x"),
+	}
+
+	if !reflect.DeepEqual(g.GraphDependencies(), expectedEdges) {
+		t.Fatalf("unexpected edges %v", g.GraphDependencies())
+	}
+
+	// Labels are ignored by default
+	if kind := g.EdgeKindOf("d", "b c"); kind != soydepend.KindRuntime {
+		t.Fatalf("unexpected kind %s", kind.String())
+	}
+
+	g, err = soydepend.ParseDOT(strings.NewReader(input), soydepend.ParseDOTOptions{
+		Direction: soydepend.DependencyToDependent,
+		EdgeKinds: true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !g.DependsOnDirectly("a", "b c") || !g.DependsOnDirectly("d", "e") {
+		t.Fatalf("unexpected edges %v", g.GraphDependencies())
+	}
+
+	if kind := g.EdgeKindOf("b c", "d"); kind != soydepend.KindBuild|soydepend.KindTest {
+		t.Fatalf("unexpected kind %s", kind.String())
+	}
+}
+
+func TestParseDOTRoundTrip(t *testing.T) {
+	g := dotTestGraph(t)
+	g.AddNode(`back\slash`)
+
+	var b strings.Builder
+	err := g.WriteDOT(&b, soydepend.DOTOptions[string]{RankLayers: true})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	parsed, err := soydepend.ParseDOT(strings.NewReader(b.String()), soydepend.ParseDOTOptions{UseLabels: true, EdgeKinds: true})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertSameGraph(t, &g, &parsed)
+}
+
+func TestParseDOTErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+		err   error
+	}{
+		{input: "digraph {\n\ta -> b\n\tb -> c\n\tc -> a\n}", line: 4, err: soydepend.ErrCircularDependency},
+		{input: "digraph {\n\ta -> a\n}", line: 2, err: soydepend.ErrDependsOnSelf},
+		{input: "digraph {\n\ta -> b [label=bogus]\n}", line: 2, err: soydepend.ErrInvalidEdgeKind},
+		{input: "digraph {\n\ta -> b [label=none]\n}", line: 2, err: soydepend.ErrInvalidEdgeKind},
+		{input: "graph {\n\ta -- b\n}", line: 1, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\ta -- b\n}", line: 2, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\ta -> \n}", line: 3, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\ta [label=]\n}", line: 2, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\t\"a\n\n", line: 2, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n/* a\n\n", line: 2, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\ta\n\t$\n}", line: 3, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {\n\ta\n", line: 3, err: soydepend.ErrDOTSyntax},
+		{input: "digraph {}\ndigraph {}", line: 2, err: soydepend.ErrDOTSyntax},
+	}
+
+	for i := range tests {
+		_, err := soydepend.ParseDOT(strings.NewReader(tests[i].input), soydepend.ParseDOTOptions{EdgeKinds: true})
+
+		var errDOT *soydepend.DOTError
+		if !errors.As(err, &errDOT) {
+			t.Fatalf("test %d: expecting DOTError, got %v", i, err)
+		}
+
+		if errDOT.Line != tests[i].line {
+			t.Fatalf("test %d: expecting error at line %d, got %v", i, tests[i].line, err)
+		}
+
+		if tests[i].err != nil && !errors.Is(err, tests[i].err) {
+			t.Fatalf("test %d: expecting %v, got %v", i, tests[i].err, err)
+		}
+
+		// Rejected edges are reported
+		var errEdge *soydepend.EdgeError[string]
+		if errors.Is(err, soydepend.ErrDOTSyntax) == errors.As(err, &errEdge) {
+			t.Fatalf("test %d: unexpected error %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package soydepend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrDependsOnSelf      = errors.New("node depends on self")
+	ErrDependentExists    = errors.New("dependent exists")
+	ErrNoSuchDependency   = errors.New("no such direct dependency")
+	ErrCircularDependency = errors.New("circular dependency")
+	ErrIntegrity          = errors.New("graph integrity violated")
+	ErrNoSuchNode         = errors.New("no such node")
+	ErrPinned             = errors.New("node is pinned")
+	ErrNoDigest           = errors.New("node has no digest")
+	ErrInvalidEdgeKind    = errors.New("invalid edge kind")
+)
+
+// Dangling describes how a reference in graph is invalid
+type Dangling uint8
+
+const (
+	DanglingKey       Dangling = iota + 1 // Key of the edge map is not a node in graph
+	DanglingNode                          // Node in the edge set is not a node in graph
+	DanglingInverse                       // Edge Key-Node is missing from the other edge map
+	DanglingKind                          // Edge Key-Node has no edge kinds
+	DanglingOrder                         // Edge Key-Node is out of topological order
+	DanglingUnordered                     // Node Key has no position in topological order
+)
+
+// DanglingReference is an invalid reference found in graph
+type DanglingReference[T comparable] struct {
+	Dangling Dangling
+	Edges    string // Name of the map holding the reference, e.g. "dependents" or "dependencies"
+	Key      T      // Key of the map
+	Node     T      // Node in the edge set of Key, zero value if Dangling is DanglingKey or DanglingUnordered
+}
+
+// IntegrityError lists every invalid reference found in graph.
+// It wraps ErrIntegrity.
+type IntegrityError[T comparable] struct {
+	References []DanglingReference[T]
+}
+
+// PinnedError is returned when a removal would remove a pinned node.
+// It wraps ErrPinned.
+type PinnedError[T comparable] struct {
+	Node T // The pinned node
+
+	// Chain is the removal cascade that reached Node,
+	// i.e. target -> ... -> Node, where each node depends on the previous one.
+	// Chain is just [Node] if Node is the removal target.
+	Chain []T
+}
+
+// CycleError is returned by Depend when the new edge would close a cycle.
+// It wraps ErrCircularDependency, so errors.Is keeps working.
+type CycleError[T comparable] struct {
+	// Path is the dependency chain that closes the loop,
+	// i.e. dependency -> ... -> dependent -> dependency
+	Path []T
+}
+
+func (e *CycleError[T]) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCircularDependency.Error(), joinNodes(e.Path))
+}
+
+func (e *CycleError[T]) Unwrap() error {
+	return ErrCircularDependency
+}
+
+func (e *PinnedError[T]) Error() string {
+	if len(e.Chain) <= 1 {
+		return fmt.Sprintf("%s: %v", ErrPinned.Error(), e.Node)
+	}
+
+	return fmt.Sprintf("%s: %v (via %s)", ErrPinned.Error(), e.Node, joinNodes(e.Chain))
+}
+
+func (e *PinnedError[T]) Unwrap() error {
+	return ErrPinned
+}
+
+func (d Dangling) String() string {
+	switch d {
+	case DanglingKey:
+		return "dangling key"
+	case DanglingNode:
+		return "dangling node"
+	case DanglingInverse:
+		return "missing inverse edge"
+	case DanglingKind:
+		return "missing edge kind"
+	case DanglingOrder:
+		return "edge out of order"
+	case DanglingUnordered:
+		return "missing order"
+	}
+
+	return fmt.Sprintf("Dangling(%d)", d)
+}
+
+func (r DanglingReference[T]) String() string {
+	if r.Dangling == DanglingKey || r.Dangling == DanglingUnordered {
+		return fmt.Sprintf("%s %s[%v]", r.Dangling.String(), r.Edges, r.Key)
+	}
+
+	return fmt.Sprintf("%s %s[%v]: %v", r.Dangling.String(), r.Edges, r.Key, r.Node)
+}
+
+func (e *IntegrityError[T]) Error() string {
+	refs := make([]string, len(e.References))
+	for i := range e.References {
+		refs[i] = e.References[i].String()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrIntegrity.Error(), strings.Join(refs, ", "))
+}
+
+func (e *IntegrityError[T]) Unwrap() error {
+	return ErrIntegrity
+}
+
+func integrityError[T comparable](dangling Dangling, edges string, key, node T) error {
+	return &IntegrityError[T]{
+		References: []DanglingReference[T]{{Dangling: dangling, Edges: edges, Key: key, Node: node}},
+	}
+}
+
+func joinNodes[T any](nodes []T) string {
+	s := make([]string, len(nodes))
+	for i := range nodes {
+		s[i] = fmt.Sprint(nodes[i])
+	}
+
+	return strings.Join(s, " -> ")
+}
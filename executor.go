@@ -0,0 +1,350 @@
+package soydepend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+// FailMode controls what Executor does after a node fails
+type FailMode uint8
+
+const (
+	FailFast  FailMode = iota // Skip dependents of failed nodes, cancel running nodes and start no more nodes after the first failure
+	KeepGoing                 // Skip dependents of failed nodes, but keep running other nodes
+)
+
+// Status is the outcome of a node run by Executor
+type Status uint8
+
+const (
+	StatusSucceeded Status = iota + 1 // Node ran successfully
+	StatusFailed                      // Node ran and returned an error
+	StatusSkipped                     // Node did not run because one of its dependencies failed or was skipped
+	StatusCancelled                   // Node did not run or was interrupted because the run was cancelled
+	StatusRetried                     // Node succeeded after failed attempts
+)
+
+// Task runs a single node. It should return early if ctx is cancelled.
+type Task[T comparable] func(ctx context.Context, node T) error
+
+// RetryPolicy controls how Executor retries failed attempts of a node.
+// The zero value runs each node once without timeout.
+type RetryPolicy struct {
+	MaxAttempts int           // Maximum number of attempts, 1 if <= 0
+	Timeout     time.Duration // Timeout of each attempt, no timeout if <= 0
+	Backoff     time.Duration // Delay before the first retry
+	MaxBackoff  time.Duration // Upper bound of retry delays, unbounded if <= 0
+	Multiplier  float64       // Growth factor of retry delays, 2 if <= 0
+}
+
+// Result is the outcome of a single node
+type Result struct {
+	Status   Status
+	Attempts int   // Number of attempts made, 0 if the node never ran
+	Err      error // Error returned by the last attempt, if any
+}
+
+// Report maps every node to its Result
+type Report[T comparable] map[T]Result
+
+// NodeError is a task error of a node
+type NodeError[T comparable] struct {
+	Node T
+	Err  error
+}
+
+// Executor runs tasks on graph nodes in dependency order. A node is started
+// as soon as all of its direct dependencies have succeeded, so that independent
+// chains of nodes do not wait for each other.
+type Executor[T comparable] struct {
+	graph *Graph[T]
+
+	Concurrency int               // Maximum number of nodes running at once, defaults to runtime.GOMAXPROCS if <= 0
+	Mode        FailMode          // What to do after a node fails
+	Policy      RetryPolicy       // Default retry policy of all nodes
+	Policies    map[T]RetryPolicy // Per-node retry policies, overriding Policy
+}
+
+type taskResult[T comparable] struct {
+	node      T
+	attempts  int
+	abandoned <-chan struct{} // Closed when the abandoned last attempt returns, nil if it was not abandoned
+	err       error
+}
+
+func (s Status) String() string {
+	switch s {
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusRetried:
+		return "retried"
+	}
+
+	return fmt.Sprintf("Status(%d)", s)
+}
+
+func (e *NodeError[T]) Error() string {
+	return fmt.Sprintf("node %v: %s", e.Node, e.Err.Error())
+}
+
+func (e *NodeError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Nodes returns nodes in r with the given status
+func (r Report[T]) Nodes(status Status) Set[T] {
+	nodes := make(Set[T])
+	for node, result := range r {
+		if result.Status == status {
+			nodes[node] = struct{}{}
+		}
+	}
+
+	return nodes
+}
+
+func NewExecutor[T comparable](g *Graph[T]) *Executor[T] {
+	return &Executor[T]{graph: g}
+}
+
+// Run runs task on every node of the graph, and reports the outcome of every node.
+// The graph is snapshotted when Run is called, so it may be modified during the run.
+//
+// Failed attempts are retried according to the node's RetryPolicy.
+// If the policy has a timeout, an attempt that outlives its timeout is counted
+// as failed with context.DeadlineExceeded, even if the task ignores its context.
+// A node never runs twice at once: a timed out attempt is waited for before retrying,
+// while the last attempt is abandoned, but keeps its slot of Concurrency until it returns.
+// Run does not wait for abandoned attempts before returning.
+//
+// The returned error joins *NodeError[T] of every failed node,
+// as well as ctx.Err() if ctx is cancelled before all nodes are done.
+func (e *Executor[T]) Run(ctx context.Context, task Task[T]) (Report[T], error) {
+	g := e.graph.Clone()
+
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := make(Report[T], g.store.Len())
+	pending := make(map[T]int, g.store.Len()) // Number of unfinished direct dependencies
+	var ready []T
+
+	g.store.RangeNodes(func(node T) bool {
+		pending[node] = g.store.NumDependencies(node)
+		if pending[node] == 0 {
+			ready = append(ready, node)
+		}
+
+		return true
+	})
+
+	results := make(chan taskResult[T])
+	released := make(chan struct{}, g.store.Len()) // Signaled by abandoned attempts returning, buffered so they never block
+	done := ctx.Done()
+	running := 0
+	abandoned := 0 // Abandoned attempts still holding their slots
+	stopping := false
+
+	var errs []error
+
+	for {
+		if ctx.Err() != nil {
+			stopping = true
+		}
+
+		for !stopping && len(ready) != 0 && running+abandoned < concurrency {
+			node := popQueue(&ready)
+			running++
+
+			go func() {
+				attempts, abandoned, err := runRetry(runCtx, e.policyOf(node), task, node)
+				results <- taskResult[T]{node: node, attempts: attempts, abandoned: abandoned, err: err}
+			}()
+		}
+
+		// Nodes left ready are only waiting for abandoned attempts to release their slots
+		if running == 0 && (stopping || len(ready) == 0) {
+			break
+		}
+
+		select {
+		case <-done:
+			stopping = true
+			done = nil
+
+		case <-released:
+			abandoned--
+
+		case result := <-results:
+			running--
+
+			if result.abandoned != nil {
+				abandoned++
+				go func() {
+					<-result.abandoned
+					released <- struct{}{}
+				}()
+			}
+
+			switch {
+			case result.err == nil:
+				status := StatusSucceeded
+				if result.attempts > 1 {
+					status = StatusRetried
+				}
+
+				report[result.node] = Result{Status: status, Attempts: result.attempts}
+
+				g.store.RangeDependents(result.node, func(dependent T, _ EdgeKind) bool {
+					pending[dependent]--
+					if pending[dependent] == 0 && !contains(report, dependent) {
+						ready = append(ready, dependent)
+					}
+
+					return true
+				})
+
+			case runCtx.Err() != nil && errors.Is(result.err, context.Canceled):
+				report[result.node] = Result{Status: StatusCancelled, Attempts: result.attempts, Err: result.err}
+
+			default:
+				report[result.node] = Result{Status: StatusFailed, Attempts: result.attempts, Err: result.err}
+				errs = append(errs, &NodeError[T]{Node: result.node, Err: result.err})
+
+				for dependent := range g.Dependents(result.node) {
+					report[dependent] = Result{Status: StatusSkipped}
+				}
+
+				if e.Mode == FailFast {
+					stopping = true
+					cancel()
+				}
+			}
+		}
+	}
+
+	g.store.RangeNodes(func(node T) bool {
+		if !contains(report, node) {
+			report[node] = Result{Status: StatusCancelled}
+		}
+
+		return true
+	})
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return report, errors.Join(errs...)
+}
+
+func (e *Executor[T]) policyOf(node T) RetryPolicy {
+	policy, ok := e.Policies[node]
+	if !ok {
+		return e.Policy
+	}
+
+	return policy
+}
+
+// runRetry runs task on node until it succeeds or policy gives up,
+// and returns the number of attempts made and the last error.
+// If the last attempt was abandoned, the returned channel is closed once it returns.
+func runRetry[T comparable](ctx context.Context, policy RetryPolicy, task Task[T], node T) (int, <-chan struct{}, error) {
+	maxAttempts := max(policy.MaxAttempts, 1)
+	multiplier := policy.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		abandoned, err := runAttempt(ctx, policy.Timeout, task, node)
+		if err == nil || attempt == maxAttempts || ctx.Err() != nil {
+			return attempt, abandoned, err
+		}
+
+		// Wait for the timed out attempt, so that node never runs twice at once
+		if abandoned != nil {
+			select {
+			case <-ctx.Done():
+				return attempt, abandoned, ctx.Err()
+			case <-abandoned:
+			}
+		}
+
+		if delay > 0 {
+			if policy.MaxBackoff > 0 {
+				delay = min(delay, policy.MaxBackoff)
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return attempt, nil, ctx.Err()
+			case <-timer.C:
+			}
+
+			delay = nextBackoff(delay, multiplier, policy.MaxBackoff)
+		}
+	}
+}
+
+// nextBackoff grows delay by multiplier, capped by maxBackoff if it is positive,
+// and saturating instead of overflowing time.Duration
+func nextBackoff(delay time.Duration, multiplier float64, maxBackoff time.Duration) time.Duration {
+	next := float64(delay) * multiplier
+	if next >= math.MaxInt64 {
+		delay = math.MaxInt64
+	} else {
+		delay = time.Duration(next)
+	}
+
+	if maxBackoff > 0 {
+		delay = min(delay, maxBackoff)
+	}
+
+	return delay
+}
+
+// runAttempt runs task once. If timeout is positive, the attempt is abandoned
+// once it outlives timeout, so that a task ignoring its context cannot stall the run.
+// The returned channel of an abandoned attempt is closed once the task returns.
+func runAttempt[T comparable](ctx context.Context, timeout time.Duration, task Task[T], node T) (<-chan struct{}, error) {
+	if timeout <= 0 {
+		return nil, task(ctx, node)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		err = task(attemptCtx, node)
+	}()
+
+	select {
+	case <-finished:
+		return nil, err
+	case <-attemptCtx.Done():
+		return finished, attemptCtx.Err()
+	}
+}
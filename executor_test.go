@@ -0,0 +1,367 @@
+package soydepend_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestExecutorOrder(t *testing.T) {
+	g := initTestGraph(t)
+	exec := soydepend.NewExecutor(&g)
+
+	var mut sync.Mutex
+	finished := make(soydepend.Set[string])
+
+	report, err := exec.Run(context.Background(), func(_ context.Context, node string) error {
+		mut.Lock()
+		defer mut.Unlock()
+
+		for dependency := range g.DependenciesDirect(node) {
+			if !finished.Contains(dependency) {
+				return fmt.Errorf("%s started before dependency %s finished", node, dependency)
+			}
+		}
+
+		finished[node] = struct{}{}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertSet(t, report.Nodes(soydepend.StatusSucceeded), g.GraphNodes())
+}
+
+func TestExecutorConcurrency(t *testing.T) {
+	g := soydepend.New[int]()
+	for i := 1; i <= 20; i++ {
+		g.AddNode(i)
+	}
+
+	exec := soydepend.NewExecutor(&g)
+	exec.Concurrency = 3
+
+	var running, maxRunning atomic.Int32
+	_, err := exec.Run(context.Background(), func(context.Context, int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			prev := maxRunning.Load()
+			if n <= prev || maxRunning.CompareAndSwap(prev, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if n := maxRunning.Load(); n > 3 {
+		t.Fatalf("expecting at most 3 running nodes, got %d", n)
+	}
+}
+
+// TestExecutorEagerStart tests that b starts as soon as a is done,
+// without waiting for slow node c in the same layer as a.
+func TestExecutorEagerStart(t *testing.T) {
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, map[string][]string{
+		"b": {"a"},
+	})
+
+	g.AddNode("c")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bStarted := make(chan struct{})
+	exec := soydepend.NewExecutor(&g)
+	exec.Concurrency = 2
+
+	report, err := exec.Run(ctx, func(ctx context.Context, node string) error {
+		switch node {
+		case "b":
+			close(bStarted)
+		case "c":
+			select {
+			case <-bStarted:
+			case <-ctx.Done():
+				return errors.New("b did not start while c was running")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertSet(t, report.Nodes(soydepend.StatusSucceeded), soydepend.NodeSet("a", "b", "c"))
+}
+
+func TestExecutorKeepGoing(t *testing.T) {
+	g := initTestGraph(t)
+	exec := soydepend.NewExecutor(&g)
+	exec.Mode = soydepend.KeepGoing
+
+	errFoo := errors.New("foo")
+	report, err := exec.Run(context.Background(), func(_ context.Context, node string) error {
+		if node == "a" || node == "0" {
+			return errFoo
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, errFoo) {
+		t.Fatal("expecting errFoo, got", err)
+	}
+
+	var errNode *soydepend.NodeError[string]
+	if !errors.As(err, &errNode) {
+		t.Fatalf("expecting NodeError, got %T", err)
+	}
+
+	assertSet(t, report.Nodes(soydepend.StatusFailed), soydepend.NodeSet("a", "0"))
+	assertSet(t, report.Nodes(soydepend.StatusSkipped), soydepend.NodeSet("b", "c", "d", "1"))
+	assertSet(t, report.Nodes(soydepend.StatusSucceeded), soydepend.NodeSet("x", "y"))
+
+	if !errors.Is(report["a"].Err, errFoo) {
+		t.Fatal("expecting errFoo in report, got", report["a"].Err)
+	}
+}
+
+func TestExecutorFailFast(t *testing.T) {
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, map[string][]string{
+		"b": {"a"},
+		"y": {"x"},
+	})
+
+	exec := soydepend.NewExecutor(&g)
+	exec.Concurrency = 2
+
+	errFoo := errors.New("foo")
+	report, err := exec.Run(context.Background(), func(ctx context.Context, node string) error {
+		switch node {
+		case "a":
+			return errFoo
+		case "x":
+			<-ctx.Done() // x is interrupted by a failing
+			return ctx.Err()
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, errFoo) {
+		t.Fatal("expecting errFoo, got", err)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		t.Fatal("interrupted nodes should not be reported as errors")
+	}
+
+	// Dependents of failed nodes are skipped, other unfinished nodes are cancelled
+	assertSet(t, report.Nodes(soydepend.StatusFailed), soydepend.NodeSet("a"))
+	assertSet(t, report.Nodes(soydepend.StatusSkipped), soydepend.NodeSet("b"))
+	assertSet(t, report.Nodes(soydepend.StatusCancelled), soydepend.NodeSet("x", "y"))
+}
+
+func TestExecutorCancel(t *testing.T) {
+	g := initTestGraph(t)
+	exec := soydepend.NewExecutor(&g)
+	exec.Concurrency = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	report, err := exec.Run(ctx, func(context.Context, string) error {
+		cancel()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expecting context.Canceled, got", err)
+	}
+
+	if n := len(report.Nodes(soydepend.StatusSucceeded)); n != 1 {
+		t.Fatalf("expecting only 1 node to run, got %d", n)
+	}
+
+	if n := len(report.Nodes(soydepend.StatusCancelled)); n != len(g.GraphNodes())-1 {
+		t.Fatalf("expecting all other nodes to be cancelled, got %d", n)
+	}
+}
+
+func TestExecutorRetry(t *testing.T) {
+	g := initTestGraph(t)
+	exec := soydepend.NewExecutor(&g)
+	exec.Mode = soydepend.KeepGoing
+	exec.Policy = soydepend.RetryPolicy{MaxAttempts: 3}
+	exec.Policies = map[string]soydepend.RetryPolicy{
+		"0": {MaxAttempts: 2},
+	}
+
+	errFlaky := errors.New("flaky")
+
+	var mut sync.Mutex
+	attempts := make(map[string]int)
+
+	report, err := exec.Run(context.Background(), func(_ context.Context, node string) error {
+		mut.Lock()
+		defer mut.Unlock()
+
+		attempts[node]++
+		switch node {
+		case "a":
+			if attempts[node] < 3 {
+				return errFlaky
+			}
+		case "0":
+			return errFlaky
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, errFlaky) {
+		t.Fatal("expecting errFlaky, got", err)
+	}
+
+	assertSet(t, report.Nodes(soydepend.StatusRetried), soydepend.NodeSet("a"))
+	assertSet(t, report.Nodes(soydepend.StatusFailed), soydepend.NodeSet("0"))
+	assertSet(t, report.Nodes(soydepend.StatusSkipped), soydepend.NodeSet("1"))
+	assertSet(t, report.Nodes(soydepend.StatusSucceeded), soydepend.NodeSet("b", "c", "d", "x", "y"))
+
+	expected := map[string]int{"a": 3, "0": 2, "b": 1, "1": 0}
+	for node, n := range expected {
+		if report[node].Attempts != n {
+			t.Fatalf("unexpected attempts for %s: expecting %d, got %d", node, n, report[node].Attempts)
+		}
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, map[string][]string{
+		"b": {"a"},
+	})
+
+	g.AddNode("c")
+
+	exec := soydepend.NewExecutor(&g)
+	exec.Mode = soydepend.KeepGoing
+	exec.Concurrency = 1
+	exec.Policy = soydepend.RetryPolicy{MaxAttempts: 2, Timeout: 10 * time.Millisecond}
+
+	var running, overlaps atomic.Int32
+	report, err := exec.Run(context.Background(), func(_ context.Context, node string) error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+
+		defer running.Add(-1)
+
+		if node == "a" {
+			time.Sleep(30 * time.Millisecond) // a ignores its context
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expecting context.DeadlineExceeded, got", err)
+	}
+
+	if result := report["a"]; result.Status != soydepend.StatusFailed || result.Attempts != 2 {
+		t.Fatalf("unexpected result for a: %+v", result)
+	}
+
+	if status := report["b"].Status; status != soydepend.StatusSkipped {
+		t.Fatalf("unexpected status for b: %s", status.String())
+	}
+
+	if status := report["c"].Status; status != soydepend.StatusSucceeded {
+		t.Fatalf("unexpected status for c: %s", status.String())
+	}
+
+	// Neither the retry of a nor c started while an abandoned attempt of a was running
+	if n := overlaps.Load(); n != 0 {
+		t.Fatalf("%d tasks started while another was running", n)
+	}
+}
+
+func TestExecutorBackoff(t *testing.T) {
+	g := soydepend.New[string]()
+	g.AddNode("a")
+
+	// Huge multipliers are capped instead of overflowing delays
+	for _, multiplier := range []float64{0, 1e30} {
+		exec := soydepend.NewExecutor(&g)
+		exec.Policy = soydepend.RetryPolicy{
+			MaxAttempts: 4,
+			Backoff:     5 * time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+			Multiplier:  multiplier,
+		}
+
+		var starts []time.Time
+		report, err := exec.Run(context.Background(), func(context.Context, string) error {
+			starts = append(starts, time.Now())
+			if len(starts) < 4 {
+				return errors.New("flaky")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if report["a"].Status != soydepend.StatusRetried {
+			t.Fatalf("unexpected status: %s", report["a"].Status.String())
+		}
+
+		// Delays are 5ms, 10ms, then capped at 10ms
+		minDelays := []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}
+		for i, minDelay := range minDelays {
+			if delay := starts[i+1].Sub(starts[i]); delay < minDelay {
+				t.Fatalf("multiplier %v: retry %d started after %s, expecting at least %s", multiplier, i+1, delay, minDelay)
+			}
+		}
+	}
+}
+
+func TestExecutorCancelBackoff(t *testing.T) {
+	g := soydepend.New[string]()
+	g.AddNode("a")
+
+	exec := soydepend.NewExecutor(&g)
+	exec.Policy = soydepend.RetryPolicy{MaxAttempts: 2, Backoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	report, err := exec.Run(ctx, func(context.Context, string) error {
+		time.AfterFunc(10*time.Millisecond, cancel) // Cancel during backoff
+		return errors.New("flaky")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expecting context.Canceled, got", err)
+	}
+
+	if result := report["a"]; result.Status != soydepend.StatusCancelled || result.Attempts != 1 {
+		t.Fatalf("unexpected result for a: %+v", result)
+	}
+}
@@ -0,0 +1,131 @@
+package soydepend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+)
+
+// Hash is a Merkle hash of a node and its whole dependency closure
+type Hash [sha256.Size]byte
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// SetDigest sets the caller-supplied digest of node, e.g. a hash of its content,
+// and invalidates cached hashes of node and its deep dependents.
+// It returns ErrNoSuchNode if node is not in g.
+func (g *Graph[T]) SetDigest(node T, digest []byte) error {
+	if !g.store.HasNode(node) {
+		return ErrNoSuchNode
+	}
+
+	g.digests[node] = bytes.Clone(digest)
+	g.invalidateHash(node)
+
+	return nil
+}
+
+// Digest returns a copy of the digest of node set with SetDigest
+func (g *Graph[T]) Digest(node T) ([]byte, bool) {
+	digest, ok := g.digests[node]
+	return bytes.Clone(digest), ok
+}
+
+// Hash returns the Merkle hash of node, computed from the digest of node
+// and hashes of its direct dependencies along with the edge kinds.
+// Any change to a digest, edge or edge kind in the dependency closure of node
+// changes its hash, while the hash is independent of map iteration order.
+//
+// Hashes are cached, and invalidated by SetDigest, Depend, Undepend and Delete.
+// It returns ErrNoSuchNode if node is not in g, and ErrNoDigest
+// if a node in the dependency closure has no digest.
+func (g *Graph[T]) Hash(node T) (Hash, error) {
+	if !g.store.HasNode(node) {
+		return Hash{}, ErrNoSuchNode
+	}
+
+	// Iterative post-order walk, so that deep graphs do not overflow the stack
+	stack := []T{node}
+	for len(stack) != 0 {
+		current := stack[len(stack)-1]
+		if _, ok := g.hashes[current]; ok {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		pushed := false
+		g.store.RangeDependencies(current, func(dependency T, _ EdgeKind) bool {
+			if _, ok := g.hashes[dependency]; !ok {
+				stack = append(stack, dependency)
+				pushed = true
+			}
+
+			return true
+		})
+
+		if pushed {
+			continue
+		}
+
+		digest, ok := g.digests[current]
+		if !ok {
+			return Hash{}, fmt.Errorf("%w: %v", ErrNoDigest, current)
+		}
+
+		g.hashes[current] = g.merkle(current, digest)
+		stack = stack[:len(stack)-1]
+	}
+
+	return g.hashes[node], nil
+}
+
+// merkle hashes digest of node with cached hashes of its direct dependencies
+func (g *Graph[T]) merkle(node T, digest []byte) Hash {
+	children := make([][]byte, 0, g.store.NumDependencies(node))
+	g.store.RangeDependencies(node, func(dependency T, kind EdgeKind) bool {
+		hash := g.hashes[dependency]
+		children = append(children, append(hash[:], byte(kind)))
+
+		return true
+	})
+
+	slices.SortFunc(children, bytes.Compare)
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, uint64(len(digest)))
+	h.Write(digest)
+	_ = binary.Write(h, binary.BigEndian, uint64(len(children)))
+
+	for _, child := range children {
+		h.Write(child)
+	}
+
+	var hash Hash
+	copy(hash[:], h.Sum(nil))
+
+	return hash
+}
+
+// invalidateHash drops cached hashes of node and its deep dependents.
+// Hashes are only cached after hashes of all dependencies are cached,
+// so the walk stops at nodes without cached hashes.
+func (g *Graph[T]) invalidateHash(node T) {
+	queue := []T{node}
+	for len(queue) != 0 {
+		current := popQueue(&queue)
+		if _, ok := g.hashes[current]; !ok {
+			continue
+		}
+
+		delete(g.hashes, current)
+		g.store.RangeDependents(current, func(dependent T, _ EdgeKind) bool {
+			queue = append(queue, dependent)
+			return true
+		})
+	}
+}
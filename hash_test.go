@@ -0,0 +1,144 @@
+package soydepend_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func initTestHashGraph(t *testing.T, edges [][2]string) soydepend.Graph[string] {
+	g := soydepend.New[string]()
+	for _, edge := range edges {
+		if err := g.Depend(edge[0], edge[1]); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for node := range g.GraphNodes() {
+		if err := g.SetDigest(node, []byte("digest-"+node)); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	return g
+}
+
+func mustHash(t *testing.T, g *soydepend.Graph[string], node string) soydepend.Hash {
+	hash, err := g.Hash(node)
+	if err != nil {
+		t.Fatalf("unexpected error hashing %s: %v", node, err)
+	}
+
+	return hash
+}
+
+func TestHash(t *testing.T) {
+	edges := [][2]string{{"b", "a"}, {"c", "a"}, {"d", "b"}, {"d", "c"}, {"y", "x"}}
+	g := initTestHashGraph(t, edges)
+
+	_, err := g.Hash("z")
+	if !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatal("expecting ErrNoSuchNode, got", err)
+	}
+
+	d := mustHash(t, &g, "d")
+	y := mustHash(t, &g, "y")
+
+	// Hashes are independent of insertion order
+	reversed := make([][2]string, len(edges))
+	for i := range edges {
+		reversed[len(edges)-1-i] = edges[i]
+	}
+
+	other := initTestHashGraph(t, reversed)
+	if hash := mustHash(t, &other, "d"); hash != d {
+		t.Fatalf("hash depends on insertion order: %s != %s", hash.String(), d.String())
+	}
+
+	// Changes below d change its hash, but not hashes of unrelated nodes
+	if err := g.SetDigest("a", []byte("changed")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if mustHash(t, &g, "d") == d {
+		t.Fatal("hash of d should change with digest of a")
+	}
+
+	if mustHash(t, &g, "y") != y {
+		t.Fatal("hash of y should not change with digest of a")
+	}
+
+	if err := g.SetDigest("a", []byte("digest-a")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if mustHash(t, &g, "d") != d {
+		t.Fatal("hash of d should be restored with digest of a")
+	}
+
+	// Edge changes
+	if err := g.Undepend("d", "c"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if mustHash(t, &g, "d") == d {
+		t.Fatal("hash of d should change after undepend")
+	}
+
+	if err := g.Depend("d", "c", soydepend.KindBuild); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if mustHash(t, &g, "d") == d {
+		t.Fatal("hash of d should depend on edge kinds")
+	}
+
+	if err := g.Undepend("d", "c", soydepend.KindBuild); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := g.Depend("d", "c"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if mustHash(t, &g, "d") != d {
+		t.Fatal("hash of d should be restored with edge d->c")
+	}
+
+	g.Delete("c")
+	g.AssertRelationships()
+
+	if mustHash(t, &g, "d") == d {
+		t.Fatal("hash of d should change after deleting c")
+	}
+}
+
+func TestHashNoDigest(t *testing.T) {
+	g := initTestGraph(t)
+	if err := g.SetDigest("z", nil); !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatal("expecting ErrNoSuchNode, got", err)
+	}
+
+	for _, node := range []string{"d", "c"} {
+		if err := g.SetDigest(node, []byte(node)); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	_, err := g.Hash("d")
+	if !errors.Is(err, soydepend.ErrNoDigest) {
+		t.Fatal("expecting ErrNoDigest, got", err)
+	}
+
+	if err := g.SetDigest("a", []byte("a")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	mustHash(t, &g, "d")
+
+	digest, ok := g.Digest("a")
+	if !ok || string(digest) != "a" {
+		t.Fatalf("unexpected digest of a: %q", digest)
+	}
+}
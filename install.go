@@ -0,0 +1,87 @@
+package soydepend
+
+import "fmt"
+
+// InstallReason tells why a node is in graph, like install reasons in package managers.
+// Nodes are added with InstallDependency reason by default.
+type InstallReason uint8
+
+const (
+	InstallDependency InstallReason = iota // Node is in graph only to satisfy its dependents
+	InstallExplicit                        // Node was added on purpose, and is never autoremoved as orphan
+)
+
+func (r InstallReason) String() string {
+	switch r {
+	case InstallDependency:
+		return "dependency"
+	case InstallExplicit:
+		return "explicit"
+	}
+
+	return fmt.Sprintf("InstallReason(%d)", r)
+}
+
+// SetInstallReason sets install reason of node.
+// It returns ErrNoSuchNode if node is not in g.
+func (g *Graph[T]) SetInstallReason(node T, reason InstallReason) error {
+	if !g.store.HasNode(node) {
+		return ErrNoSuchNode
+	}
+
+	switch reason {
+	case InstallExplicit:
+		g.explicit[node] = struct{}{}
+	case InstallDependency:
+		delete(g.explicit, node)
+	default:
+		return fmt.Errorf("unknown install reason %d", reason)
+	}
+
+	return nil
+}
+
+// InstallReasonOf returns install reason of node
+func (g *Graph[T]) InstallReasonOf(node T) InstallReason {
+	if g.explicit.Contains(node) {
+		return InstallExplicit
+	}
+
+	return InstallDependency
+}
+
+// Orphans returns nodes with InstallDependency reason that have no dependents,
+// like pacman -Qdt. Isolated nodes with InstallDependency reason are also orphans,
+// while pinned nodes never are.
+func (g *Graph[T]) Orphans() Set[T] {
+	orphans := make(Set[T])
+
+	g.store.RangeNodes(func(node T) bool {
+		if !g.explicit.Contains(node) && !g.pinned.Contains(node) && g.store.NumDependents(node) == 0 {
+			orphans[node] = struct{}{}
+		}
+
+		return true
+	})
+
+	return orphans
+}
+
+// RemoveOrphans removes orphans until there are none left,
+// i.e. dependencies left orphaned by the removal are also removed.
+// It returns all removed nodes.
+func (g *Graph[T]) RemoveOrphans() Set[T] {
+	removed := make(Set[T])
+
+	for {
+		orphans := g.Orphans()
+		if len(orphans) == 0 {
+			return removed
+		}
+
+		for orphan := range orphans {
+			g.Delete(orphan)
+			removed[orphan] = struct{}{}
+		}
+	}
+}
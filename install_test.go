@@ -0,0 +1,121 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestInstallReason(t *testing.T) {
+	g := initTestGraph(t)
+
+	if reason := g.InstallReasonOf("c"); reason != soydepend.InstallDependency {
+		t.Fatal("unexpected default install reason", reason)
+	}
+
+	err := g.SetInstallReason("z", soydepend.InstallExplicit)
+	if !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatal("expecting ErrNoSuchNode, got", err)
+	}
+
+	err = g.SetInstallReason("c", soydepend.InstallExplicit)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if reason := g.InstallReasonOf("c"); reason != soydepend.InstallExplicit {
+		t.Fatal("unexpected install reason", reason)
+	}
+
+	plan, err := g.PlanRemoveAutoRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if removes := plan.Removes(); !reflect.DeepEqual(removes, soydepend.NodeSet("d")) {
+		t.Fatal("explicit node c should not be planned for removal", removes)
+	}
+
+	// Explicit c is kept, even though d was its only dependent
+	g.RemoveAutoRemove("d")
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("d"))
+	assertContainsAll(t, g, soydepend.NodeSet("a", "c"))
+
+	// Explicit targets and dependents are still removed
+	g.RemoveForce("a")
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("a", "c"))
+
+	// Re-added node starts with default reason
+	g.AddNode("c")
+	g.AssertRelationships()
+	if reason := g.InstallReasonOf("c"); reason != soydepend.InstallDependency {
+		t.Fatal("unexpected install reason after re-adding", reason)
+	}
+}
+
+// TestInstallReasonDefault tests that nodes are only explicit when set so,
+// regardless of how and in which order they are added
+func TestInstallReasonDefault(t *testing.T) {
+	for _, edges := range [][][2]string{
+		{{"b", "a"}, {"c", "b"}},
+		{{"c", "b"}, {"b", "a"}},
+	} {
+		g := soydepend.New[string]()
+		g.AddNode("z")
+
+		for _, edge := range edges {
+			if err := g.Depend(edge[0], edge[1]); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+		}
+
+		for node := range g.GraphNodes() {
+			if reason := g.InstallReasonOf(node); reason != soydepend.InstallDependency {
+				t.Fatalf("unexpected install reason of %s: %s", node, reason.String())
+			}
+		}
+
+		g.RemoveAutoRemove("c")
+		g.AssertRelationships()
+		assertNotContains(t, g, soydepend.NodeSet("a", "b", "c"))
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	g := initTestGraph(t)
+
+	err := g.SetInstallReason("d", soydepend.InstallExplicit)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if orphans := g.Orphans(); !reflect.DeepEqual(orphans, soydepend.NodeSet("b", "y", "1")) {
+		t.Fatal("unexpected orphans", orphans)
+	}
+
+	removed := g.RemoveOrphans()
+	g.AssertRelationships()
+	if !reflect.DeepEqual(removed, soydepend.NodeSet("b", "y", "1", "x", "0")) {
+		t.Fatal("unexpected removed orphans", removed)
+	}
+
+	if nodes := g.GraphNodes(); !reflect.DeepEqual(nodes, soydepend.NodeSet("a", "c", "d")) {
+		t.Fatal("unexpected remaining nodes", nodes)
+	}
+
+	if orphans := g.Orphans(); len(orphans) != 0 {
+		t.Fatal("unexpected orphans after RemoveOrphans", orphans)
+	}
+
+	err = g.SetInstallReason("d", soydepend.InstallDependency)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.RemoveOrphans()
+	assertEmptyGraph(t, g)
+}
@@ -0,0 +1,191 @@
+package soydepend
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// graphJSON is the JSON document of Graph.
+// Nodes, dependents and dependencies are sorted by their JSON encodings, so that
+// equal graphs always have the same document.
+type graphJSON[T comparable] struct {
+	Nodes        []T                   `json:"nodes"`
+	Dependencies []dependenciesJSON[T] `json:"dependencies"`
+}
+
+// dependenciesJSON lists direct dependencies of a node with at least one dependency
+type dependenciesJSON[T comparable] struct {
+	Dependent    T        `json:"dependent"`
+	Dependencies []T      `json:"dependencies"`
+	Kinds        []string `json:"kinds,omitempty"` // Kinds of each edge like EdgeKind.String, omitted if every edge is only KindRuntime
+}
+
+// EdgeError is an error about a dependent->dependency edge
+type EdgeError[T comparable] struct {
+	Edge Edge[T]
+	Err  error
+}
+
+func (e *EdgeError[T]) Error() string {
+	return fmt.Sprintf("edge %v -> %v: %s", e.Edge.Dependent, e.Edge.Dependency, e.Err.Error())
+}
+
+func (e *EdgeError[T]) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes nodes and edges of g as a JSON document like:
+//
+//	{
+//	  "nodes": ["a", "b", "c", "d"],
+//	  "dependencies": [
+//	    {"dependent": "b", "dependencies": ["a"]},
+//	    {"dependent": "c", "dependencies": ["a", "b"], "kinds": ["build", "runtime|test"]}
+//	  ]
+//	}
+//
+// Every node is listed in nodes, including isolated nodes, and kinds are only
+// listed if some edge of the dependent is not just KindRuntime.
+// Lists are sorted by JSON encodings of nodes, so the document is stable.
+//
+// Other states of g, e.g. install reasons and pins, are not encoded.
+// Like other methods of Graph, MarshalJSON has a pointer receiver, so marshal &g.
+func (g *Graph[T]) MarshalJSON() ([]byte, error) {
+	keys := make(map[T]string, g.store.Len())
+	nodes := make([]T, 0, g.store.Len())
+
+	var err error
+	g.store.RangeNodes(func(node T) bool {
+		var key []byte
+		key, err = json.Marshal(node)
+		if err != nil {
+			return false
+		}
+
+		keys[node] = string(key)
+		nodes = append(nodes, node)
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := func(a, b T) int { return strings.Compare(keys[a], keys[b]) }
+	slices.SortFunc(nodes, byKey)
+
+	doc := graphJSON[T]{Nodes: nodes, Dependencies: []dependenciesJSON[T]{}}
+	for _, node := range nodes {
+		if g.store.NumDependencies(node) == 0 {
+			continue
+		}
+
+		dependencies := adjacentSet(g.store.RangeDependencies, node).Slice()
+		slices.SortFunc(dependencies, byKey)
+
+		kinds := make([]string, len(dependencies))
+		runtimeOnly := true
+		for i := range dependencies {
+			kind := g.store.EdgeKind(node, dependencies[i])
+			kinds[i] = kind.String()
+			runtimeOnly = runtimeOnly && kind == KindRuntime
+		}
+
+		if runtimeOnly {
+			kinds = nil
+		}
+
+		doc.Dependencies = append(doc.Dependencies, dependenciesJSON[T]{
+			Dependent:    node,
+			Dependencies: dependencies,
+			Kinds:        kinds,
+		})
+	}
+
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON replaces nodes and edges of g with those in a document from MarshalJSON.
+// Edges are added with Depend, so cyclic documents are rejected.
+// Install reasons, pins and other states of g are reset,
+// so every decoded node is InstallDependency.
+//
+// If an edge is invalid, UnmarshalJSON returns *EdgeError[T] wrapping the reason,
+// e.g. *CycleError[T], and g is left untouched.
+func (g *Graph[T]) UnmarshalJSON(data []byte) error {
+	var doc graphJSON[T]
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+
+	decoded := g.emptied()
+	decoded.AddNodes(doc.Nodes...)
+
+	for _, entry := range doc.Dependencies {
+		if entry.Kinds != nil && len(entry.Kinds) != len(entry.Dependencies) {
+			return fmt.Errorf("dependent %v has %d kinds for %d dependencies", entry.Dependent, len(entry.Kinds), len(entry.Dependencies))
+		}
+
+		for i, dependency := range entry.Dependencies {
+			edge := Edge[T]{Dependent: entry.Dependent, Dependency: dependency}
+
+			kind := KindRuntime
+			if entry.Kinds != nil {
+				kind, err = parseEdgeKind(entry.Kinds[i])
+				if err != nil {
+					return &EdgeError[T]{Edge: edge, Err: err}
+				}
+			}
+
+			err = decoded.Depend(edge.Dependent, edge.Dependency, kind)
+			if err != nil {
+				return &EdgeError[T]{Edge: edge, Err: err}
+			}
+		}
+	}
+
+	// Nodes dropped by the new document are deleted, e.g. to drop values of ValueGraph
+	if g.onDelete != nil {
+		g.store.RangeNodes(func(node T) bool {
+			if !decoded.store.HasNode(node) {
+				g.onDelete(node)
+			}
+
+			return true
+		})
+	}
+
+	*g = decoded
+
+	return nil
+}
+
+// emptied returns a new empty graph with the same kind of store, pin policy and
+// delete hook as g, or a graph from New if g is the zero Graph
+func (g *Graph[T]) emptied() Graph[T] {
+	var s Store[T]
+	switch g.store.(type) {
+	case nil, *mapStore[T]:
+		s = newMapStore[T]()
+	case *compactStore[T]:
+		s = newCompactStore[T]()
+	default:
+		// Stores from NewWithStore cannot be constructed here, so they are cloned and emptied
+		s = g.store.Clone()
+		for _, node := range g.nodeSet().Slice() {
+			s.DeleteNode(node)
+		}
+
+		s.Compact()
+	}
+
+	emptied := newGraph[T](s)
+	emptied.pinPolicy = g.pinPolicy
+	emptied.onDelete = g.onDelete
+
+	return emptied
+}
@@ -0,0 +1,173 @@
+package soydepend_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	g := soydepend.New[string]()
+	g.AddNode("isolated")
+
+	for _, edge := range []struct {
+		dependent, dependency string
+		kinds                 []soydepend.EdgeKind
+	}{
+		{dependent: "b", dependency: "a"},
+		{dependent: "c", dependency: "b", kinds: []soydepend.EdgeKind{soydepend.KindBuild, soydepend.KindTest}},
+		{dependent: "c", dependency: "a"},
+		{dependent: "d", dependency: "c"},
+	} {
+		if err := g.Depend(edge.dependent, edge.dependency, edge.kinds...); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	expected := `{"nodes":["a","b","c","d","isolated"],"dependencies":[` +
+		`{"dependent":"b","dependencies":["a"]},` +
+		`{"dependent":"c","dependencies":["a","b"],"kinds":["runtime","build|test"]},` +
+		`{"dependent":"d","dependencies":["c"]}]}`
+
+	// Documents are stable despite map iteration order
+	for i := 0; i < 10; i++ {
+		data, err := json.Marshal(&g)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if string(data) != expected {
+			t.Fatalf("unexpected document %s, expecting %s", data, expected)
+		}
+	}
+
+	for i, decoded := range []soydepend.Graph[string]{{}, soydepend.New[string](), soydepend.NewCompact[string]()} {
+		if i != 0 {
+			decoded.AddNode("stale") // Zero Graph cannot have nodes
+			decoded.AddNode("a")
+			if err := decoded.SetInstallReason("a", soydepend.InstallExplicit); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+		}
+
+		err := json.Unmarshal([]byte(expected), &decoded)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		assertSameGraph(t, &g, &decoded)
+
+		// Install reasons are reset, whether nodes are only listed in nodes or also in edges
+		for node := range decoded.GraphNodes() {
+			if reason := decoded.InstallReasonOf(node); reason != soydepend.InstallDependency {
+				t.Fatalf("unexpected install reason of %s: %s", node, reason.String())
+			}
+		}
+	}
+
+	empty := soydepend.New[string]()
+	data, err := json.Marshal(&empty)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if string(data) != `{"nodes":[],"dependencies":[]}` {
+		t.Fatalf("unexpected document of empty graph %s", data)
+	}
+}
+
+func TestUnmarshalJSONInvalid(t *testing.T) {
+	tests := []struct {
+		doc  string
+		edge *soydepend.Edge[string] // Offending edge, nil if the document is malformed
+		err  error
+	}{
+		{
+			doc: `{"nodes":["a","b","c"],"dependencies":[` +
+				`{"dependent":"b","dependencies":["a"]},` +
+				`{"dependent":"c","dependencies":["b"]},` +
+				`{"dependent":"a","dependencies":["c"]}]}`,
+			edge: &soydepend.Edge[string]{Dependent: "a", Dependency: "c"},
+			err:  soydepend.ErrCircularDependency,
+		},
+		{
+			doc:  `{"dependencies":[{"dependent":"a","dependencies":["a"]}]}`,
+			edge: &soydepend.Edge[string]{Dependent: "a", Dependency: "a"},
+			err:  soydepend.ErrDependsOnSelf,
+		},
+		{
+			doc:  `{"dependencies":[{"dependent":"b","dependencies":["a"],"kinds":["runtime|bogus"]}]}`,
+			edge: &soydepend.Edge[string]{Dependent: "b", Dependency: "a"},
+			err:  soydepend.ErrInvalidEdgeKind,
+		},
+		{
+			doc:  `{"dependencies":[{"dependent":"b","dependencies":["a"],"kinds":["none"]}]}`,
+			edge: &soydepend.Edge[string]{Dependent: "b", Dependency: "a"},
+			err:  soydepend.ErrInvalidEdgeKind,
+		},
+		{
+			doc:  `{"dependencies":[{"dependent":"b","dependencies":["a"],"kinds":[""]}]}`,
+			edge: &soydepend.Edge[string]{Dependent: "b", Dependency: "a"},
+			err:  soydepend.ErrInvalidEdgeKind,
+		},
+		{
+			doc: `{"dependencies":[{"dependent":"b","dependencies":["a"],"kinds":["runtime","build","test"]}]}`,
+		},
+		{
+			doc: `{"nodes":[1]}`,
+		},
+	}
+
+	for i := range tests {
+		g := soydepend.New[string]()
+		if err := g.Depend("y", "x"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		err := json.Unmarshal([]byte(tests[i].doc), &g)
+		if err == nil {
+			t.Fatalf("test %d: expecting error", i)
+		}
+
+		if tests[i].err != nil && !errors.Is(err, tests[i].err) {
+			t.Fatalf("test %d: expecting %v, got %v", i, tests[i].err, err)
+		}
+
+		var errEdge *soydepend.EdgeError[string]
+		if errors.As(err, &errEdge) != (tests[i].edge != nil) {
+			t.Fatalf("test %d: unexpected error %v", i, err)
+		}
+
+		if tests[i].edge != nil && errEdge.Edge != *tests[i].edge {
+			t.Fatalf("test %d: unexpected edge %v, expecting %v", i, errEdge.Edge, *tests[i].edge)
+		}
+
+		// g is left untouched
+		if !reflect.DeepEqual(g.GraphNodes(), soydepend.NodeSet("x", "y")) || !g.DependsOnDirectly("y", "x") {
+			t.Fatalf("test %d: graph modified by failed unmarshal", i)
+		}
+	}
+}
+
+func TestUnmarshalJSONValueGraph(t *testing.T) {
+	g := soydepend.NewValueGraph[string, int]()
+	g.Put("a", 1)
+	g.Put("b", 2)
+
+	err := json.Unmarshal([]byte(`{"nodes":["a","c"]}`), &g)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !reflect.DeepEqual(g.Values(), map[string]int{"a": 1}) {
+		t.Fatalf("unexpected values %v", g.Values())
+	}
+
+	g.Delete("a")
+	if len(g.Values()) != 0 {
+		t.Fatalf("values not dropped after unmarshal: %v", g.Values())
+	}
+}
@@ -0,0 +1,130 @@
+package soydepend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EdgeKind is a bit set of kinds of a dependent->dependency edge.
+// An edge can have multiple kinds, e.g. KindBuild|KindRuntime.
+//
+// Kinds given as filters must not be 0 or have bits outside KindAll.
+// Methods returning errors reject them with ErrInvalidEdgeKind,
+// and other methods return nil or leave g untouched.
+type EdgeKind uint8
+
+const (
+	KindRuntime  EdgeKind = 1 << iota // Dependency is needed at runtime, the default kind of new edges
+	KindBuild                         // Dependency is needed at build time
+	KindOptional                      // Dependency is optional
+	KindTest                          // Dependency is needed to run tests
+
+	KindAll = KindRuntime | KindBuild | KindOptional | KindTest
+)
+
+var kindNames = []struct {
+	kind EdgeKind
+	name string
+}{
+	{kind: KindRuntime, name: "runtime"},
+	{kind: KindBuild, name: "build"},
+	{kind: KindOptional, name: "optional"},
+	{kind: KindTest, name: "test"},
+}
+
+func (k EdgeKind) String() string {
+	if k == 0 {
+		return "none"
+	}
+
+	var names []string
+	for _, kn := range kindNames {
+		if k&kn.kind != 0 {
+			names = append(names, kn.name)
+		}
+	}
+
+	if unknown := k &^ KindAll; unknown != 0 {
+		names = append(names, fmt.Sprintf("EdgeKind(%d)", uint8(unknown)))
+	}
+
+	return strings.Join(names, "|")
+}
+
+// parseEdgeKind parses names of kinds joined by "|" like in EdgeKind.String
+func parseEdgeKind(s string) (EdgeKind, error) {
+	var kind EdgeKind
+
+outer:
+	for _, name := range strings.Split(s, "|") {
+		for _, kn := range kindNames {
+			if name == kn.name {
+				kind |= kn.kind
+				continue outer
+			}
+		}
+
+		return 0, fmt.Errorf("%w: unknown name %q", ErrInvalidEdgeKind, name)
+	}
+
+	return kind, nil
+}
+
+// Has returns whether k has any of the kinds in other
+func (k EdgeKind) Has(other EdgeKind) bool {
+	return k&other != 0
+}
+
+// EdgeKindOf returns kinds of edge dependent->dependency, or 0 if there is no such edge
+func (g *Graph[T]) EdgeKindOf(dependent, dependency T) EdgeKind {
+	return g.store.EdgeKind(dependent, dependency)
+}
+
+// hasDependents returns whether node has any dependents via edges of kinds in mask
+func (g *Graph[T]) hasDependents(node T, mask EdgeKind) bool {
+	if mask == KindAll {
+		return g.store.NumDependents(node) != 0
+	}
+
+	found := false
+	g.store.RangeDependents(node, func(_ T, kind EdgeKind) bool {
+		found = kind&mask != 0
+		return !found
+	})
+
+	return found
+}
+
+// checkKinds returns ErrInvalidEdgeKind if any of kinds is 0 or has bits outside KindAll
+func checkKinds(kinds []EdgeKind) error {
+	for _, kind := range kinds {
+		if kind == 0 || kind&^KindAll != 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidEdgeKind, kind.String())
+		}
+	}
+
+	return nil
+}
+
+// kindMask combines kinds into a single EdgeKind, or returns fallback if kinds is empty
+func kindMask(kinds []EdgeKind, fallback EdgeKind) EdgeKind {
+	if len(kinds) == 0 {
+		return fallback
+	}
+
+	var mask EdgeKind
+	for i := range kinds {
+		mask |= kinds[i]
+	}
+
+	return mask
+}
+
+func copyKinds[T comparable](kinds map[T]map[T]EdgeKind) map[T]map[T]EdgeKind {
+	copied := make(map[T]map[T]EdgeKind)
+	for k, v := range kinds {
+		copied[k] = copyMap(v)
+	}
+
+	return copied
+}
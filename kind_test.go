@@ -0,0 +1,264 @@
+package soydepend_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func initTestKindGraph(t *testing.T) soydepend.Graph[string] {
+	g := soydepend.New[string]()
+	edges := []struct {
+		dependent  string
+		dependency string
+		kinds      []soydepend.EdgeKind
+	}{
+		{dependent: "app", dependency: "lib"},
+		{dependent: "app", dependency: "compiler", kinds: []soydepend.EdgeKind{soydepend.KindBuild}},
+		{dependent: "app", dependency: "docs", kinds: []soydepend.EdgeKind{soydepend.KindOptional}},
+		{dependent: "app", dependency: "testlib", kinds: []soydepend.EdgeKind{soydepend.KindTest}},
+		{dependent: "lib", dependency: "libc", kinds: []soydepend.EdgeKind{soydepend.KindRuntime}},
+		{dependent: "lib", dependency: "libc", kinds: []soydepend.EdgeKind{soydepend.KindBuild}},
+		{dependent: "compiler", dependency: "libc", kinds: []soydepend.EdgeKind{soydepend.KindRuntime}},
+	}
+
+	for _, edge := range edges {
+		err := g.Depend(edge.dependent, edge.dependency, edge.kinds...)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	g.AssertRelationships()
+
+	return g
+}
+
+func TestEdgeKinds(t *testing.T) {
+	g := initTestKindGraph(t)
+
+	if kind := g.EdgeKindOf("app", "lib"); kind != soydepend.KindRuntime {
+		t.Fatal("unexpected default edge kind", kind)
+	}
+
+	if kind := g.EdgeKindOf("lib", "libc"); kind != soydepend.KindRuntime|soydepend.KindBuild {
+		t.Fatal("kinds should be merged on existing edge, got", kind)
+	}
+
+	if kind := g.EdgeKindOf("libc", "lib"); kind != 0 {
+		t.Fatal("unexpected kind for non-existent edge", kind)
+	}
+
+	if s := g.EdgeKindOf("lib", "libc").String(); s != "runtime|build" {
+		t.Fatal("unexpected kind string", s)
+	}
+
+	assertSet(t, g.Dependencies("app"), soydepend.NodeSet("lib", "libc", "compiler", "docs", "testlib"))
+	assertSet(t, g.Dependencies("app", soydepend.KindRuntime), soydepend.NodeSet("lib", "libc"))
+	assertSet(t, g.Dependencies("app", soydepend.KindBuild), soydepend.NodeSet("compiler"))
+	assertSet(t, g.Dependencies("app", soydepend.KindBuild, soydepend.KindRuntime), soydepend.NodeSet("lib", "libc", "compiler"))
+	assertSet(t, g.Dependents("libc", soydepend.KindBuild), soydepend.NodeSet("lib"))
+	assertSet(t, g.Dependents("libc", soydepend.KindRuntime), soydepend.NodeSet("lib", "compiler", "app"))
+
+	assertLayers(t, &g, []soydepend.Set[string]{
+		soydepend.NodeSet("libc", "docs", "testlib"),
+		soydepend.NodeSet("lib", "compiler"),
+		soydepend.NodeSet("app"),
+	})
+
+	layers := g.Layers(soydepend.KindBuild)
+	expected := []soydepend.Set[string]{
+		soydepend.NodeSet("libc", "compiler", "docs", "testlib"),
+		soydepend.NodeSet("lib", "app"),
+	}
+
+	if !reflect.DeepEqual(layers, expected) {
+		t.Fatalf("unexpected build layers: expecting %v, got %v", expected, layers)
+	}
+
+	layers = g.Layers(soydepend.KindOptional)
+	expected = []soydepend.Set[string]{
+		soydepend.NodeSet("lib", "libc", "compiler", "docs", "testlib"),
+		soydepend.NodeSet("app"),
+	}
+
+	if !reflect.DeepEqual(layers, expected) {
+		t.Fatalf("unexpected optional layers: expecting %v, got %v", expected, layers)
+	}
+}
+
+func TestUndependKinds(t *testing.T) {
+	g := initTestKindGraph(t)
+
+	err := g.Undepend("app", "docs", soydepend.KindBuild)
+	if !errors.Is(err, soydepend.ErrNoSuchDependency) {
+		t.Fatal("expecting ErrNoSuchDependency, got", err)
+	}
+
+	err = g.Undepend("lib", "libc", soydepend.KindBuild)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	if kind := g.EdgeKindOf("lib", "libc"); kind != soydepend.KindRuntime {
+		t.Fatal("unexpected kind after undepend", kind)
+	}
+
+	err = g.Undepend("lib", "libc", soydepend.KindRuntime)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	if g.DependsOnDirectly("lib", "libc") {
+		t.Fatal("edge without kinds should be removed")
+	}
+
+	// Re-depending starts with fresh kinds
+	err = g.Depend("lib", "libc", soydepend.KindTest)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if kind := g.EdgeKindOf("lib", "libc"); kind != soydepend.KindTest {
+		t.Fatal("unexpected kind after re-depending", kind)
+	}
+
+	err = g.Undepend("app", "compiler")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	if kind := g.EdgeKindOf("app", "compiler"); kind != 0 {
+		t.Fatal("unexpected kind after undepend", kind)
+	}
+}
+
+func TestRemoveKinds(t *testing.T) {
+	g := initTestKindGraph(t)
+
+	// Autoremove ignoring optional and test edges
+	plan, err := g.PlanRemoveAutoRemove("app", soydepend.KindRuntime, soydepend.KindBuild)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertSet(t, plan.Removes(), soydepend.NodeSet("app", "lib", "libc", "compiler"))
+
+	g.RemoveAutoRemove("app", soydepend.KindRuntime, soydepend.KindBuild)
+	g.AssertRelationships()
+	assertSet(t, g.GraphNodes(), soydepend.NodeSet("docs", "testlib"))
+
+	// Force removal following only build edges
+	g = initTestKindGraph(t)
+	err = g.TryRemoveForce("libc", soydepend.KindBuild)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertSet(t, g.GraphNodes(), soydepend.NodeSet("app", "compiler", "docs", "testlib"))
+	if g.DependsOnDirectly("compiler", "libc") {
+		t.Fatal("unfollowed edge to removed node should be broken")
+	}
+
+	// Only build dependents prevent removal
+	g = initTestKindGraph(t)
+	err = g.Remove("docs", soydepend.KindBuild, soydepend.KindRuntime)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.Remove("compiler", soydepend.KindBuild)
+	if !errors.Is(err, soydepend.ErrDependentExists) {
+		t.Fatal("expecting ErrDependentExists, got", err)
+	}
+
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("docs"))
+}
+
+func TestInvalidEdgeKinds(t *testing.T) {
+	g := initTestKindGraph(t)
+	before := g.Clone()
+
+	invalids := [][]soydepend.EdgeKind{
+		{0},
+		{soydepend.KindBuild, 0},
+		{soydepend.KindAll + 1},
+		{1 << 7},
+	}
+
+	for _, kinds := range invalids {
+		// New and existing edges are both rejected
+		for _, edge := range [][2]string{{"app", "new"}, {"app", "lib"}} {
+			err := g.Depend(edge[0], edge[1], kinds...)
+			if !errors.Is(err, soydepend.ErrInvalidEdgeKind) {
+				t.Fatalf("kinds %v: expecting ErrInvalidEdgeKind from Depend, got %v", kinds, err)
+			}
+
+			err = g.Undepend(edge[0], edge[1], kinds...)
+			if !errors.Is(err, soydepend.ErrInvalidEdgeKind) {
+				t.Fatalf("kinds %v: expecting ErrInvalidEdgeKind from Undepend, got %v", kinds, err)
+			}
+		}
+
+		err := g.Begin().Depend("app", "new", kinds...).Commit()
+		if !errors.Is(err, soydepend.ErrInvalidEdgeKind) {
+			t.Fatalf("kinds %v: expecting ErrInvalidEdgeKind from Commit, got %v", kinds, err)
+		}
+
+		// libc has dependents, so removals must not ignore them
+		errs := map[string]error{
+			"Remove":              g.Remove("libc", kinds...),
+			"TryRemoveForce":      g.TryRemoveForce("libc", kinds...),
+			"TryRemoveAutoRemove": g.TryRemoveAutoRemove("app", kinds...),
+			"WriteDOT":            g.WriteDOT(io.Discard, soydepend.DOTOptions[string]{Kinds: kinds}),
+			"WriteMermaid":        g.WriteMermaid(io.Discard, soydepend.MermaidOptions[string]{Kinds: kinds}),
+		}
+
+		_, errs["PlanRemove"] = g.PlanRemove("libc", kinds...)
+		_, errs["PlanRemoveForce"] = g.PlanRemoveForce("libc", kinds...)
+		_, errs["PlanRemoveAutoRemove"] = g.PlanRemoveAutoRemove("app", kinds...)
+		_, errs["Rebuild"] = g.Rebuild(func(string) (bool, error) { return true, nil }, kinds...)
+
+		for method, err := range errs {
+			if !errors.Is(err, soydepend.ErrInvalidEdgeKind) {
+				t.Fatalf("kinds %v: expecting ErrInvalidEdgeKind from %s, got %v", kinds, method, err)
+			}
+		}
+
+		// Methods without errors remove nothing and return nothing
+		g.RemoveForce("libc", kinds...)
+		g.RemoveAutoRemove("app", kinds...)
+
+		if err := g.MarkDirty("libc"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if g.Dependencies("app", kinds...) != nil || g.Dependents("libc", kinds...) != nil ||
+			g.Layers(kinds...) != nil || g.DirtySet(kinds...) != nil || g.RebuildOrder(kinds...) != nil {
+			t.Fatalf("kinds %v: expecting nil results from queries", kinds)
+		}
+
+		g.MarkClean("libc")
+	}
+
+	g.AssertRelationships()
+	assertSameGraph(t, &before, &g)
+
+	if layers := g.Layers(); len(layers) != 3 {
+		t.Fatalf("unexpected layers %v", layers)
+	}
+}
+
+func assertSet[T comparable](t *testing.T, actual, expected soydepend.Set[T]) {
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected set: expecting %v, got %v", expected, actual)
+	}
+}
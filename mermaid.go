@@ -0,0 +1,188 @@
+package soydepend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// MermaidOptions configures WriteMermaid. The zero value writes every node top-down.
+type MermaidOptions[T comparable] struct {
+	Direction string              // Direction of the flowchart, e.g. "LR", "TD" if empty
+	Kinds     []EdgeKind          // Only write edges with any of the kinds, and use them for Layers and Depth
+	Label     func(node T) string // Label of node, fmt.Sprint by default
+
+	Layers bool // Group nodes of each layer from Layers in a subgraph
+
+	Center T   // Node whose neighbourhood is written if Depth > 0
+	Depth  int // Only write nodes within Depth edges of Center in either direction, or every node if Depth <= 0
+}
+
+// WriteMermaid writes g to w as a Mermaid flowchart, with edges pointing
+// from dependents to dependencies. Like WriteDOT, nodes are written in topological order,
+// sorted by label within each layer, and edges with kinds other than just KindRuntime
+// are labeled with their kinds.
+//
+// Node IDs are escaped from fmt.Sprint of nodes, e.g. "lib c" is n_lib_20c,
+// so they are stable across graphs and neighbourhoods. Nodes whose IDs collide get numbered suffixes.
+//
+// It returns ErrNoSuchNode if Depth > 0 and Center is not in g,
+// and ErrInvalidEdgeKind if any of Kinds is 0 or not within KindAll.
+func (g *Graph[T]) WriteMermaid(w io.Writer, opts MermaidOptions[T]) error {
+	err := checkKinds(opts.Kinds)
+	if err != nil {
+		return err
+	}
+
+	label := opts.Label
+	if label == nil {
+		label = func(node T) string { return fmt.Sprint(node) }
+	}
+
+	direction := opts.Direction
+	if direction == "" {
+		direction = "TD"
+	}
+
+	mask := kindMask(opts.Kinds, KindAll)
+
+	var included Set[T]
+	if opts.Depth > 0 {
+		if !g.store.HasNode(opts.Center) {
+			return ErrNoSuchNode
+		}
+
+		included = g.neighbourhood(opts.Center, opts.Depth, mask)
+	}
+
+	var layers [][]T
+	for _, layer := range g.sortedLayers(label, opts.Kinds...) {
+		if included != nil {
+			layer = slices.DeleteFunc(layer, func(node T) bool { return !included.Contains(node) })
+		}
+
+		layers = append(layers, layer)
+	}
+
+	ids := make(map[T]string, g.store.Len())
+	order := make(map[T]int, g.store.Len())
+	taken := make(map[string]bool, g.store.Len())
+
+	for _, layer := range layers {
+		for _, node := range layer {
+			base := mermaidID(fmt.Sprint(node))
+			id := base
+			for i := 2; taken[id]; i++ {
+				id = fmt.Sprintf("%s_%d", base, i)
+			}
+
+			ids[node] = id
+			order[node] = len(order)
+			taken[id] = true
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "flowchart %s\n", direction)
+
+	for i, layer := range layers {
+		if len(layer) == 0 {
+			continue
+		}
+
+		indent := "    "
+		if opts.Layers {
+			fmt.Fprintf(bw, "    subgraph layer_%d [\"Layer %d\"]\n", i, i)
+			indent += "    "
+		}
+
+		for _, node := range layer {
+			fmt.Fprintf(bw, "%s%s[\"%s\"]\n", indent, ids[node], escapeMermaid(label(node)))
+		}
+
+		if opts.Layers {
+			fmt.Fprint(bw, "    end\n")
+		}
+	}
+
+	for _, layer := range layers {
+		for _, dependent := range layer {
+			for _, dependency := range g.sortedDependencies(dependent, order) {
+				kind := g.store.EdgeKind(dependent, dependency)
+				if _, ok := ids[dependency]; !ok || kind&mask == 0 {
+					continue
+				}
+
+				arrow := "-->"
+				if kind != KindRuntime {
+					arrow = fmt.Sprintf("-->|\"%s\"|", escapeMermaid(strings.ReplaceAll(kind.String(), "|", ", ")))
+				}
+
+				fmt.Fprintf(bw, "    %s %s %s\n", ids[dependent], arrow, ids[dependency])
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// neighbourhood returns nodes within depth edges of kinds in mask from center in either direction
+func (g *Graph[T]) neighbourhood(center T, depth int, mask EdgeKind) Set[T] {
+	visited := Set[T]{center: struct{}{}}
+	frontier := []T{center}
+
+	visit := func(next *[]T) func(adjacent T, kind EdgeKind) bool {
+		return func(adjacent T, kind EdgeKind) bool {
+			if kind&mask != 0 && !visited.Contains(adjacent) {
+				visited[adjacent] = struct{}{}
+				*next = append(*next, adjacent)
+			}
+
+			return true
+		}
+	}
+
+	for i := 0; i < depth && len(frontier) != 0; i++ {
+		var next []T
+		for _, node := range frontier {
+			g.store.RangeDependencies(node, visit(&next))
+			g.store.RangeDependents(node, visit(&next))
+		}
+
+		frontier = next
+	}
+
+	return visited
+}
+
+// mermaidID escapes s to a Mermaid node ID prefixed with n_, keeping letters and digits,
+// and replacing other bytes, including underscores, with _ and their hex codes
+func mermaidID(s string) string {
+	var b strings.Builder
+	b.WriteString("n_")
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			b.WriteByte(c)
+			continue
+		}
+
+		fmt.Fprintf(&b, "_%02x", c)
+	}
+
+	return b.String()
+}
+
+// mermaidEscaper escapes quoted Mermaid labels with entity codes
+var mermaidEscaper = strings.NewReplacer(
+	"#", "#35;",
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"\n", "<br>",
+)
+
+func escapeMermaid(s string) string { return mermaidEscaper.Replace(s) }
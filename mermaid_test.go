@@ -0,0 +1,160 @@
+package soydepend_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestWriteMermaid(t *testing.T) {
+	g := dotTestGraph(t)
+
+	var b strings.Builder
+	err := g.WriteMermaid(&b, soydepend.MermaidOptions[string]{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `flowchart TD
+    n_a["a"]
+    n_say_20_22hi_22["say #quot;hi#quot;"]
+    n_b["b"]
+    n_c["c"]
+    n_d["d"]
+    n_b --> n_a
+    n_c -->|"build, test"| n_a
+    n_c --> n_b
+    n_d --> n_c
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+
+	// Nodes with colliding IDs are kept apart
+	h := soydepend.New[any]()
+	if err := h.Depend("1", 1); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := h.Depend("_", "1"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	b.Reset()
+	err = h.WriteMermaid(&b, soydepend.MermaidOptions[any]{})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected = `flowchart TD
+    n_1["1"]
+    n_1_2["1"]
+    n__5f["_"]
+    n_1_2 --> n_1
+    n__5f --> n_1_2
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+}
+
+func TestWriteMermaidOptions(t *testing.T) {
+	g := dotTestGraph(t)
+
+	var b strings.Builder
+	err := g.WriteMermaid(&b, soydepend.MermaidOptions[string]{
+		Direction: "LR",
+		Kinds:     []soydepend.EdgeKind{soydepend.KindRuntime},
+		Label:     strings.ToUpper,
+		Layers:    true,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `flowchart LR
+    subgraph layer_0 ["Layer 0"]
+        n_a["A"]
+        n_say_20_22hi_22["SAY #quot;HI#quot;"]
+    end
+    subgraph layer_1 ["Layer 1"]
+        n_b["B"]
+    end
+    subgraph layer_2 ["Layer 2"]
+        n_c["C"]
+    end
+    subgraph layer_3 ["Layer 3"]
+        n_d["D"]
+    end
+    n_b --> n_a
+    n_c --> n_b
+    n_d --> n_c
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+
+	// Only nodes within 1 edge of b are written, keeping IDs and layer numbers
+	b.Reset()
+	err = g.WriteMermaid(&b, soydepend.MermaidOptions[string]{
+		Layers: true,
+		Center: "b",
+		Depth:  1,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected = `flowchart TD
+    subgraph layer_0 ["Layer 0"]
+        n_a["a"]
+    end
+    subgraph layer_1 ["Layer 1"]
+        n_b["b"]
+    end
+    subgraph layer_2 ["Layer 2"]
+        n_c["c"]
+    end
+    n_b --> n_a
+    n_c -->|"build, test"| n_a
+    n_c --> n_b
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+
+	// Neighbourhoods only follow edges of the kinds
+	b.Reset()
+	err = g.WriteMermaid(&b, soydepend.MermaidOptions[string]{
+		Kinds:  []soydepend.EdgeKind{soydepend.KindBuild},
+		Center: "a",
+		Depth:  2,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected = `flowchart TD
+    n_a["a"]
+    n_c["c"]
+    n_c -->|"build, test"| n_a
+`
+
+	if b.String() != expected {
+		t.Fatalf("unexpected output:\n%s\nexpecting:\n%s", b.String(), expected)
+	}
+
+	err = g.WriteMermaid(&b, soydepend.MermaidOptions[string]{Center: "x", Depth: 1})
+	if !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatalf("expecting ErrNoSuchNode, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package soydepend
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SliceFunc returns nodes in s sorted with compare, which should return
+// a negative number when a < b, a positive number when a > b and zero when a == b.
+func (s Set[T]) SliceFunc(compare func(a, b T) int) []T {
+	slice := s.Slice()
+	slices.SortFunc(slice, compare)
+
+	return slice
+}
+
+// Sorted returns nodes in s in ascending order
+func Sorted[T cmp.Ordered](s Set[T]) []T {
+	slice := s.Slice()
+	slices.Sort(slice)
+
+	return slice
+}
+
+// LeavesFunc returns Leaves sorted with compare
+func (g *Graph[T]) LeavesFunc(compare func(a, b T) int) []T {
+	return g.Leaves().SliceFunc(compare)
+}
+
+// DependenciesFunc returns Dependencies of node sorted with compare
+func (g *Graph[T]) DependenciesFunc(node T, compare func(a, b T) int, kinds ...EdgeKind) []T {
+	return g.Dependencies(node, kinds...).SliceFunc(compare)
+}
+
+// DependentsFunc returns Dependents of node sorted with compare
+func (g *Graph[T]) DependentsFunc(node T, compare func(a, b T) int, kinds ...EdgeKind) []T {
+	return g.Dependents(node, kinds...).SliceFunc(compare)
+}
+
+// LayersFunc returns Layers with nodes in each layer sorted with compare
+func (g *Graph[T]) LayersFunc(compare func(a, b T) int, kinds ...EdgeKind) [][]T {
+	layers := g.Layers(kinds...)
+
+	sorted := make([][]T, len(layers))
+	for i := range layers {
+		sorted[i] = layers[i].SliceFunc(compare)
+	}
+
+	return sorted
+}
+
+// TopoSort returns all nodes in a single deterministic topological order,
+// i.e. every node comes after all of its dependencies.
+// Nodes are ordered layer by layer as in Layers, and sorted with compare within each layer.
+func (g *Graph[T]) TopoSort(compare func(a, b T) int, kinds ...EdgeKind) []T {
+	order := make([]T, 0, g.store.Len())
+	for _, layer := range g.LayersFunc(compare, kinds...) {
+		order = append(order, layer...)
+	}
+
+	return order
+}
@@ -0,0 +1,73 @@
+package soydepend_test
+
+import (
+	"cmp"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestSorted(t *testing.T) {
+	set := soydepend.NodeSet("c", "a", "d", "b")
+
+	if sorted := soydepend.Sorted(set); !reflect.DeepEqual(sorted, []string{"a", "b", "c", "d"}) {
+		t.Fatal("unexpected sorted slice:", sorted)
+	}
+
+	descending := func(a, b string) int { return strings.Compare(b, a) }
+	if sorted := set.SliceFunc(descending); !reflect.DeepEqual(sorted, []string{"d", "c", "b", "a"}) {
+		t.Fatal("unexpected sorted slice:", sorted)
+	}
+}
+
+func TestOrderedQueries(t *testing.T) {
+	g := initTestGraph(t)
+	compare := cmp.Compare[string]
+
+	if leaves := g.LeavesFunc(compare); !reflect.DeepEqual(leaves, []string{"0", "a", "x"}) {
+		t.Fatal("unexpected leaves:", leaves)
+	}
+
+	if dependents := g.DependentsFunc("a", compare); !reflect.DeepEqual(dependents, []string{"b", "c", "d"}) {
+		t.Fatal("unexpected dependents:", dependents)
+	}
+
+	if dependencies := g.DependenciesFunc("d", compare); !reflect.DeepEqual(dependencies, []string{"a", "c"}) {
+		t.Fatal("unexpected dependencies:", dependencies)
+	}
+
+	expected := [][]string{{"0", "a", "x"}, {"1", "b", "c", "y"}, {"d"}}
+	if layers := g.LayersFunc(compare); !reflect.DeepEqual(layers, expected) {
+		t.Fatalf("unexpected layers: expecting %v, got %v", expected, layers)
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	g := initTestGraph(t)
+	expected := []string{"0", "a", "x", "1", "b", "c", "y", "d"}
+
+	// Output is identical across runs despite map iteration order
+	for i := 0; i < 10; i++ {
+		if order := g.TopoSort(cmp.Compare[string]); !reflect.DeepEqual(order, expected) {
+			t.Fatalf("unexpected order: expecting %v, got %v", expected, order)
+		}
+	}
+
+	g = initTestKindGraph(t)
+	order := g.TopoSort(cmp.Compare[string], soydepend.KindBuild)
+
+	position := make(map[string]int, len(order))
+	for i, node := range order {
+		position[node] = i
+	}
+
+	for _, node := range order {
+		for dependency := range g.Dependencies(node, soydepend.KindBuild) {
+			if position[dependency] > position[node] {
+				t.Fatalf("%s comes before its dependency %s in %v", node, dependency, order)
+			}
+		}
+	}
+}
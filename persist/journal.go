@@ -0,0 +1,354 @@
+// Package persist keeps a soydepend.Graph on disk. Every change is journaled
+// to an append-only write-ahead log, and snapshots of the whole graph
+// periodically replace the log, so that restarts do not rebuild graphs from scratch.
+//
+// Nodes are encoded with encoding/json, so they must round-trip through it.
+package persist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/soyart/soydepend-go"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	logFile      = "wal.log"
+)
+
+var (
+	ErrBroken  = errors.New("journal broken by failed write")
+	ErrClosed  = errors.New("journal closed")
+	ErrCorrupt = errors.New("journal corrupted")
+)
+
+type Options struct {
+	SnapshotEvery int                 // Take a snapshot after this many journaled changes, never if 0
+	NoSync        bool                // Skip fsync after each change, so a crash may lose recent changes
+	PinPolicy     soydepend.PinPolicy // Pin policy of the graph, which is not journaled
+}
+
+// Journal is a Graph whose changes are journaled in a directory.
+// Journal is not safe for concurrent use.
+//
+// Each change is applied to the graph first, and only journaled if it succeeds.
+// If writing to the log then fails, the graph is ahead of the log, so every later
+// call returns ErrBroken, and the journal must be reopened.
+type Journal[T comparable] struct {
+	dir     string
+	opts    Options
+	graph   soydepend.Graph[T]
+	log     *os.File
+	seq     uint64 // Seq of the last journaled change
+	records int    // Number of records in log
+	err     error  // Sticky ErrBroken or ErrClosed
+}
+
+// Open opens the journal in dir, creating dir if needed, and restores the graph
+// from the snapshot and log in dir.
+//
+// A record torn by a crash at the end of the log is discarded and truncated,
+// so only changes whose records were completely written are restored.
+// A record is torn if it is cut short, or if its length or checksum is invalid
+// and nothing but zeros follows it. Invalid records followed by other data
+// are corruption, and Open returns ErrCorrupt.
+func Open[T comparable](dir string, opts Options) (*Journal[T], error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := readSnapshot[T](filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+
+	graph, err := snap.restore()
+	if err != nil {
+		return nil, fmt.Errorf("%w: restore snapshot: %w", ErrCorrupt, err)
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	graph.SetPinPolicy(opts.PinPolicy)
+	j := &Journal[T]{dir: dir, opts: opts, graph: graph, log: log, seq: snap.Seq}
+
+	err = j.replay()
+	if err == nil {
+		err = syncDir(dir)
+	}
+
+	if err != nil {
+		return nil, errors.Join(err, log.Close())
+	}
+
+	return j, nil
+}
+
+// replay applies records newer than the snapshot to the graph,
+// and truncates the log after the last complete record
+func (j *Journal[T]) replay() error {
+	r := bufio.NewReader(j.log)
+
+	var offset int64
+	for {
+		rec, n, err := readRecord[T](r)
+		if errors.Is(err, errInvalid) {
+			torn, zerr := onlyZeros(r)
+			if zerr != nil {
+				return zerr
+			}
+
+			if torn {
+				err = errTorn
+			}
+		}
+
+		if err == io.EOF || errors.Is(err, errTorn) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: record at offset %d: %w", ErrCorrupt, offset, err)
+		}
+
+		offset += int64(n)
+		j.records++
+
+		// Records are left in log if a crash happened between writing a snapshot and truncating the log
+		if rec.Seq <= j.seq {
+			continue
+		}
+
+		if rec.Seq != j.seq+1 {
+			return fmt.Errorf("%w: record %d follows record %d", ErrCorrupt, rec.Seq, j.seq)
+		}
+
+		err = rec.apply(&j.graph)
+		if err != nil {
+			return fmt.Errorf("%w: replay record %d: %w", ErrCorrupt, rec.Seq, err)
+		}
+
+		j.seq = rec.Seq
+	}
+
+	info, err := j.log.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() != offset {
+		err = j.log.Truncate(offset)
+		if err == nil {
+			err = j.log.Sync()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = j.log.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+// View calls f with the graph. f must not modify the graph,
+// since changes made directly to the graph are not journaled.
+func (j *Journal[T]) View(f func(g *soydepend.Graph[T]) error) error {
+	return f(&j.graph)
+}
+
+// Graph returns a copy of the graph
+func (j *Journal[T]) Graph() soydepend.Graph[T] {
+	return j.graph.Clone()
+}
+
+func (j *Journal[T]) AddNode(node T) error {
+	return j.AddNodes(node)
+}
+
+func (j *Journal[T]) AddNodes(nodes ...T) error {
+	if len(nodes) == 0 {
+		return j.err
+	}
+
+	rec := &record[T]{Op: opAddNode, Nodes: nodes}
+	return j.journal(rec, rec.apply)
+}
+
+func (j *Journal[T]) Depend(dependent, dependency T, kinds ...soydepend.EdgeKind) error {
+	rec := &record[T]{Op: opDepend, Nodes: []T{dependent, dependency}, Kind: combine(kinds)}
+	return j.journal(rec, func(g *soydepend.Graph[T]) error {
+		return g.Depend(dependent, dependency, kinds...)
+	})
+}
+
+func (j *Journal[T]) Undepend(dependent, dependency T, kinds ...soydepend.EdgeKind) error {
+	rec := &record[T]{Op: opUndepend, Nodes: []T{dependent, dependency}, Kind: combine(kinds)}
+	return j.journal(rec, func(g *soydepend.Graph[T]) error {
+		return g.Undepend(dependent, dependency, kinds...)
+	})
+}
+
+func (j *Journal[T]) SetInstallReason(node T, reason soydepend.InstallReason) error {
+	rec := &record[T]{Op: opReason, Nodes: []T{node}, Reason: reason}
+	return j.journal(rec, rec.apply)
+}
+
+func (j *Journal[T]) Pin(node T) error {
+	rec := &record[T]{Op: opPin, Nodes: []T{node}}
+	return j.journal(rec, rec.apply)
+}
+
+func (j *Journal[T]) Unpin(node T) error {
+	rec := &record[T]{Op: opUnpin, Nodes: []T{node}}
+	return j.journal(rec, rec.apply)
+}
+
+// Delete deletes target from the graph, like Graph.Delete.
+// Deleting a node not in the graph is not journaled.
+func (j *Journal[T]) Delete(target T) error {
+	if !j.graph.Contains(target) {
+		return j.err
+	}
+
+	rec := &record[T]{Op: opDelete, Nodes: []T{target}}
+	return j.journal(rec, rec.apply)
+}
+
+// Remove removes target like Graph.Remove
+func (j *Journal[T]) Remove(target T, kinds ...soydepend.EdgeKind) error {
+	return j.remove(j.graph.PlanRemove(target, kinds...))
+}
+
+// RemoveForce removes target like Graph.TryRemoveForce
+func (j *Journal[T]) RemoveForce(target T, kinds ...soydepend.EdgeKind) error {
+	return j.remove(j.graph.PlanRemoveForce(target, kinds...))
+}
+
+// RemoveAutoRemove removes target like Graph.TryRemoveAutoRemove
+func (j *Journal[T]) RemoveAutoRemove(target T, kinds ...soydepend.EdgeKind) error {
+	return j.remove(j.graph.PlanRemoveAutoRemove(target, kinds...))
+}
+
+// remove applies plan, journaling the removed nodes instead of the removal call,
+// so that replaying does not depend on pin policy, which is not journaled
+func (j *Journal[T]) remove(plan *soydepend.RemovalPlan[T], err error) error {
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Nodes) == 0 {
+		return j.err
+	}
+
+	rec := &record[T]{Op: opDelete}
+	for i := range plan.Nodes {
+		rec.Nodes = append(rec.Nodes, plan.Nodes[i].Node)
+	}
+
+	return j.journal(rec, func(g *soydepend.Graph[T]) error {
+		return g.ApplyPlan(plan)
+	})
+}
+
+// Snapshot writes a snapshot of the graph, and empties the log
+func (j *Journal[T]) Snapshot() error {
+	if j.err != nil {
+		return j.err
+	}
+
+	err := writeSnapshot(filepath.Join(j.dir, snapshotFile), takeSnapshot(&j.graph, j.seq))
+	if err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	// Records left in log are skipped by Open if truncating fails,
+	// but the end of log is no longer known
+	err = j.log.Truncate(0)
+	if err == nil {
+		_, err = j.log.Seek(0, io.SeekStart)
+	}
+
+	if err == nil {
+		err = j.log.Sync()
+	}
+
+	if err != nil {
+		j.err = fmt.Errorf("%w: truncate log: %w", ErrBroken, err)
+		return j.err
+	}
+
+	j.records = 0
+
+	return nil
+}
+
+// Close closes the log. Later calls return ErrClosed.
+func (j *Journal[T]) Close() error {
+	if errors.Is(j.err, ErrClosed) {
+		return nil
+	}
+
+	j.err = ErrClosed
+
+	return j.log.Close()
+}
+
+// journal applies rec to the graph with apply, and appends rec to log if apply succeeds
+func (j *Journal[T]) journal(rec *record[T], apply func(g *soydepend.Graph[T]) error) error {
+	if j.err != nil {
+		return j.err
+	}
+
+	rec.Seq = j.seq + 1
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	err = apply(&j.graph)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.log.Write(data)
+	if err == nil && !j.opts.NoSync {
+		err = j.log.Sync()
+	}
+
+	if err != nil {
+		j.err = fmt.Errorf("%w: %w", ErrBroken, err)
+		return j.err
+	}
+
+	j.seq = rec.Seq
+	j.records++
+
+	if j.opts.SnapshotEvery > 0 && j.records >= j.opts.SnapshotEvery {
+		err = j.Snapshot()
+		if err != nil {
+			return fmt.Errorf("change journaled, but %w", err)
+		}
+	}
+
+	return nil
+}
+
+func combine(kinds []soydepend.EdgeKind) soydepend.EdgeKind {
+	var kind soydepend.EdgeKind
+	for i := range kinds {
+		kind |= kinds[i]
+	}
+
+	return kind
+}
@@ -0,0 +1,298 @@
+package persist_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+	"github.com/soyart/soydepend-go/persist"
+)
+
+type edgeKinds map[[2]string]soydepend.EdgeKind
+
+func assertSameGraph(t *testing.T, expected, actual *soydepend.Graph[string]) {
+	t.Helper()
+
+	actual.AssertRelationships()
+
+	if !reflect.DeepEqual(expected.GraphNodes(), actual.GraphNodes()) {
+		t.Fatalf("unexpected nodes: expecting %v, got %v", expected.GraphNodes(), actual.GraphNodes())
+	}
+
+	if !reflect.DeepEqual(kindsOf(expected), kindsOf(actual)) {
+		t.Fatalf("unexpected edges: expecting %v, got %v", kindsOf(expected), kindsOf(actual))
+	}
+
+	if !reflect.DeepEqual(expected.Pinned(), actual.Pinned()) {
+		t.Fatalf("unexpected pinned nodes: expecting %v, got %v", expected.Pinned(), actual.Pinned())
+	}
+
+	for node := range expected.GraphNodes() {
+		if expected.InstallReasonOf(node) != actual.InstallReasonOf(node) {
+			t.Fatalf("unexpected install reason of %s", node)
+		}
+	}
+}
+
+func kindsOf(g *soydepend.Graph[string]) edgeKinds {
+	kinds := make(edgeKinds)
+	for dependent, dependencies := range g.GraphDependencies() {
+		for dependency := range dependencies {
+			kinds[[2]string{dependent, dependency}] = g.EdgeKindOf(dependent, dependency)
+		}
+	}
+
+	return kinds
+}
+
+func open(t *testing.T, dir string, opts persist.Options) *persist.Journal[string] {
+	t.Helper()
+
+	j, err := persist.Open[string](dir, opts)
+	if err != nil {
+		t.Fatal("unexpected error from Open:", err)
+	}
+
+	return j
+}
+
+func mustOK(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+// changes makes changes covering every journaled call, and returns
+// graphs after each change, where graphs[0] is the empty graph
+func changes(t *testing.T, j *persist.Journal[string]) []soydepend.Graph[string] {
+	graphs := []soydepend.Graph[string]{j.Graph()}
+	change := func(err error) {
+		t.Helper()
+		mustOK(t, err)
+		graphs = append(graphs, j.Graph())
+	}
+
+	change(j.AddNodes("isolated", "lonely"))
+	change(j.Depend("b", "a"))
+	change(j.Depend("c", "b", soydepend.KindBuild, soydepend.KindTest))
+	change(j.Depend("c", "b", soydepend.KindRuntime))
+	change(j.Depend("d", "c"))
+	change(j.Depend("e", "a", soydepend.KindOptional))
+	change(j.Undepend("c", "b", soydepend.KindTest))
+	change(j.SetInstallReason("b", soydepend.InstallExplicit))
+	change(j.Pin("a"))
+	change(j.RemoveAutoRemove("e"))
+	change(j.Depend("x", "d"))
+	change(j.Depend("y", "x"))
+	change(j.RemoveForce("x"))
+	change(j.Undepend("b", "a"))
+	change(j.Unpin("a"))
+	change(j.Delete("lonely"))
+	change(j.Remove("d"))
+	change(j.AddNode("z"))
+
+	// Failed calls are not journaled
+	if err := j.Depend("b", "c"); !errors.Is(err, soydepend.ErrCircularDependency) {
+		t.Fatal("expecting ErrCircularDependency, got", err)
+	}
+
+	if err := j.Remove("b"); !errors.Is(err, soydepend.ErrDependentExists) {
+		t.Fatal("expecting ErrDependentExists, got", err)
+	}
+
+	return graphs
+}
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+
+	j := open(t, dir, persist.Options{})
+	graphs := changes(t, j)
+	mustOK(t, j.Close())
+
+	if err := j.Depend("q", "p"); !errors.Is(err, persist.ErrClosed) {
+		t.Fatal("expecting ErrClosed, got", err)
+	}
+
+	expected := graphs[len(graphs)-1]
+	if !expected.Contains("isolated") || expected.Contains("lonely") {
+		t.Fatalf("unexpected nodes %v", expected.GraphNodes())
+	}
+
+	j = open(t, dir, persist.Options{})
+	restored := j.Graph()
+	assertSameGraph(t, &expected, &restored)
+
+	// Restored journals keep journaling
+	mustOK(t, j.Depend("w", "z"))
+	mustOK(t, expected.Depend("w", "z"))
+	mustOK(t, j.Close())
+
+	j = open(t, dir, persist.Options{})
+	restored = j.Graph()
+	assertSameGraph(t, &expected, &restored)
+	mustOK(t, j.Close())
+}
+
+func TestJournalSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "wal.log")
+
+	j := open(t, dir, persist.Options{SnapshotEvery: 4})
+	graphs := changes(t, j)
+	expected := graphs[len(graphs)-1]
+
+	// 18 changes with a snapshot every 4 changes leave 2 records in log
+	restored := open(t, dir, persist.Options{})
+	graph := restored.Graph()
+	assertSameGraph(t, &expected, &graph)
+	mustOK(t, restored.Close())
+
+	// Simulate a crash after writing a snapshot, but before truncating the log
+	mustOK(t, j.Depend("w", "z"))
+	mustOK(t, expected.Depend("w", "z"))
+
+	log, err := os.ReadFile(logPath)
+	mustOK(t, err)
+	mustOK(t, j.Snapshot())
+	mustOK(t, j.Close())
+
+	info, err := os.Stat(logPath)
+	mustOK(t, err)
+	if info.Size() != 0 {
+		t.Fatalf("unexpected log size %d after snapshot", info.Size())
+	}
+
+	mustOK(t, os.WriteFile(logPath, log, 0o644))
+
+	j = open(t, dir, persist.Options{})
+	graph = j.Graph()
+	assertSameGraph(t, &expected, &graph)
+
+	// Change after the stale records
+	mustOK(t, j.Delete("w"))
+	mustOK(t, j.Close())
+
+	expected.Delete("w")
+	j = open(t, dir, persist.Options{})
+	graph = j.Graph()
+	assertSameGraph(t, &expected, &graph)
+	mustOK(t, j.Close())
+}
+
+// TestJournalCrash truncates the log at every byte, as if the process
+// crashed while writing it, and checks that every complete record is restored
+func TestJournalCrash(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "wal.log")
+
+	j := open(t, dir, persist.Options{})
+	graphs := changes(t, j)
+	mustOK(t, j.Close())
+
+	log, err := os.ReadFile(logPath)
+	mustOK(t, err)
+
+	// Records are framed by little-endian payload length and checksum
+	var ends []int
+	for offset := 0; offset < len(log); {
+		offset += 8 + int(binary.LittleEndian.Uint32(log[offset:]))
+		ends = append(ends, offset)
+	}
+
+	if len(ends) != len(graphs)-1 {
+		t.Fatalf("expecting %d records, got %d", len(graphs)-1, len(ends))
+	}
+
+	for cut := 0; cut <= len(log); cut++ {
+		crashed := t.TempDir()
+		mustOK(t, os.WriteFile(filepath.Join(crashed, "wal.log"), log[:cut], 0o644))
+
+		complete := 0
+		for complete < len(ends) && ends[complete] <= cut {
+			complete++
+		}
+
+		j := open(t, crashed, persist.Options{})
+		restored := j.Graph()
+		assertSameGraph(t, &graphs[complete], &restored)
+
+		// The torn record is truncated, so new records are not appended after garbage
+		mustOK(t, j.AddNode("new"))
+		mustOK(t, j.Close())
+
+		j = open(t, crashed, persist.Options{})
+		restored = j.Graph()
+		if !restored.Contains("new") {
+			t.Fatalf("cut %d: node added after recovery is lost", cut)
+		}
+
+		mustOK(t, j.Close())
+	}
+
+	// Corrupt the last record instead of cutting it
+	corrupted := t.TempDir()
+	log[len(log)-2] ^= 0xff
+	mustOK(t, os.WriteFile(filepath.Join(corrupted, "wal.log"), log, 0o644))
+
+	j = open(t, corrupted, persist.Options{})
+	restored := j.Graph()
+	assertSameGraph(t, &graphs[len(graphs)-2], &restored)
+	mustOK(t, j.Close())
+	log[len(log)-2] ^= 0xff
+
+	// Zeros after the last record, e.g. from blocks allocated before a crash, are torn too
+	zeroed := t.TempDir()
+	mustOK(t, os.WriteFile(filepath.Join(zeroed, "wal.log"), append(slices.Clip(log), make([]byte, 64)...), 0o644))
+
+	j = open(t, zeroed, persist.Options{})
+	restored = j.Graph()
+	assertSameGraph(t, &graphs[len(graphs)-1], &restored)
+	mustOK(t, j.Close())
+
+	// Corrupting a record followed by other records is not a torn write
+	for _, corrupt := range []func(log []byte){
+		func(log []byte) { log[ends[0]-2] ^= 0xff },      // Checksum mismatch
+		func(log []byte) { log[ends[0]+3] = 0xff },       // Invalid length
+		func(log []byte) { clear(log[ends[0]:ends[1]]) }, // Zeroed record
+	} {
+		corrupted := t.TempDir()
+		broken := slices.Clone(log)
+		corrupt(broken)
+		mustOK(t, os.WriteFile(filepath.Join(corrupted, "wal.log"), broken, 0o644))
+
+		_, err := persist.Open[string](corrupted, persist.Options{})
+		if !errors.Is(err, persist.ErrCorrupt) {
+			t.Fatal("expecting ErrCorrupt from corrupted record in the middle of log, got", err)
+		}
+
+		// The log is left intact for inspection
+		kept, err := os.ReadFile(filepath.Join(corrupted, "wal.log"))
+		mustOK(t, err)
+		if !bytes.Equal(kept, broken) {
+			t.Fatal("corrupted log was modified")
+		}
+	}
+}
+
+func TestJournalCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := `{"seq":2,"nodes":["a","b"],"edges":[` +
+		`{"dependent":"a","dependency":"b","kind":1},` +
+		`{"dependent":"b","dependency":"a","kind":1}]}`
+
+	mustOK(t, os.WriteFile(filepath.Join(dir, "snapshot.json"), []byte(snapshot), 0o644))
+
+	_, err := persist.Open[string](dir, persist.Options{})
+	if !errors.Is(err, persist.ErrCorrupt) || !errors.Is(err, soydepend.ErrCircularDependency) {
+		t.Fatal("expecting ErrCorrupt from cyclic snapshot, got", err)
+	}
+}
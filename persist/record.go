@@ -0,0 +1,169 @@
+package persist
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/soyart/soydepend-go"
+)
+
+// Records are framed as a little-endian uint32 payload length,
+// a CRC-32C checksum of the payload, and then the JSON payload
+const (
+	headerSize = 8
+	maxPayload = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// errTorn is returned by readRecord for a record cut short by a crash
+	errTorn = errors.New("torn record")
+
+	// errInvalid is returned by readRecord for a record with an invalid length or checksum,
+	// which was only torn by a crash if nothing but zeros follows it
+	errInvalid = errors.New("invalid record")
+)
+
+type op string
+
+const (
+	opAddNode  op = "add"
+	opDepend   op = "depend"
+	opUndepend op = "undepend"
+	opDelete   op = "delete" // Deletes every node in Nodes, used by Delete and removals
+	opReason   op = "reason"
+	opPin      op = "pin"
+	opUnpin    op = "unpin"
+)
+
+// record is a journaled call. Nodes are its node arguments in call order.
+type record[T comparable] struct {
+	Seq    uint64                  `json:"seq"`
+	Op     op                      `json:"op"`
+	Nodes  []T                     `json:"nodes"`
+	Kind   soydepend.EdgeKind      `json:"kind,omitempty"` // Kinds arguments combined, 0 if none is given
+	Reason soydepend.InstallReason `json:"reason,omitempty"`
+}
+
+func (r *record[T]) apply(g *soydepend.Graph[T]) error {
+	nodes := 1
+	switch r.Op {
+	case opAddNode, opDelete:
+		nodes = len(r.Nodes)
+	case opDepend, opUndepend:
+		nodes = 2
+	}
+
+	if len(r.Nodes) != nodes || nodes == 0 {
+		return fmt.Errorf("%s record with %d nodes", r.Op, len(r.Nodes))
+	}
+
+	switch r.Op {
+	case opAddNode:
+		g.AddNodes(r.Nodes...)
+	case opDepend:
+		return g.Depend(r.Nodes[0], r.Nodes[1], r.kinds()...)
+	case opUndepend:
+		return g.Undepend(r.Nodes[0], r.Nodes[1], r.kinds()...)
+	case opDelete:
+		for i := range r.Nodes {
+			g.Delete(r.Nodes[i])
+		}
+	case opReason:
+		return g.SetInstallReason(r.Nodes[0], r.Reason)
+	case opPin:
+		return g.Pin(r.Nodes[0])
+	case opUnpin:
+		g.Unpin(r.Nodes[0])
+	default:
+		return fmt.Errorf("unknown record op %q", r.Op)
+	}
+
+	return nil
+}
+
+// kinds returns kinds arguments of the journaled call, i.e. none if Kind is 0
+func (r *record[T]) kinds() []soydepend.EdgeKind {
+	if r.Kind == 0 {
+		return nil
+	}
+
+	return []soydepend.EdgeKind{r.Kind}
+}
+
+func encodeRecord[T comparable](r *record[T]) ([]byte, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+
+	return append(buf, payload...), nil
+}
+
+// readRecord reads the next record from r, returning its size in bytes.
+// It returns io.EOF at a clean end of log, errTorn if the record is incomplete,
+// and errInvalid if its length or checksum is invalid.
+func readRecord[T comparable](r io.Reader) (*record[T], int, error) {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(r, header)
+	switch {
+	case err == io.EOF:
+		return nil, 0, io.EOF
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return nil, n, errTorn
+	case err != nil:
+		return nil, n, err
+	}
+
+	// Zero-filled blocks after a crash look like empty records with valid checksums
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size == 0 || size > maxPayload {
+		return nil, n, fmt.Errorf("%w: length %d", errInvalid, size)
+	}
+
+	payload := make([]byte, size)
+	m, err := io.ReadFull(r, payload)
+	switch {
+	case err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF):
+		return nil, n + m, errTorn
+	case err != nil:
+		return nil, n + m, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, n + m, fmt.Errorf("%w: checksum mismatch", errInvalid)
+	}
+
+	rec := new(record[T])
+	err = json.Unmarshal(payload, rec)
+	if err != nil {
+		return nil, n + m, fmt.Errorf("decode record: %w", err)
+	}
+
+	return rec, n + m, nil
+}
+
+// onlyZeros reports whether the rest of r is empty or zero-filled
+func onlyZeros(r io.Reader) (bool, error) {
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range rest {
+		if b != 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
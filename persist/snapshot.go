@@ -0,0 +1,148 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/soyart/soydepend-go"
+)
+
+// snapshot is the whole graph as of record Seq
+type snapshot[T comparable] struct {
+	Seq      uint64            `json:"seq"`
+	Nodes    []T               `json:"nodes"`
+	Edges    []snapshotEdge[T] `json:"edges"`
+	Explicit []T               `json:"explicit"`
+	Pinned   []T               `json:"pinned"`
+}
+
+type snapshotEdge[T comparable] struct {
+	Dependent  T                  `json:"dependent"`
+	Dependency T                  `json:"dependency"`
+	Kind       soydepend.EdgeKind `json:"kind"`
+}
+
+func takeSnapshot[T comparable](g *soydepend.Graph[T], seq uint64) *snapshot[T] {
+	s := &snapshot[T]{Seq: seq, Pinned: g.Pinned().Slice()}
+
+	// Nodes are listed in topological order, so that restoring never reorders
+	for _, layer := range g.Layers() {
+		for node := range layer {
+			s.Nodes = append(s.Nodes, node)
+			if g.InstallReasonOf(node) == soydepend.InstallExplicit {
+				s.Explicit = append(s.Explicit, node)
+			}
+
+			for dependency := range g.DependenciesDirect(node) {
+				s.Edges = append(s.Edges, snapshotEdge[T]{
+					Dependent:  node,
+					Dependency: dependency,
+					Kind:       g.EdgeKindOf(node, dependency),
+				})
+			}
+		}
+	}
+
+	return s
+}
+
+// restore rebuilds the graph with Depend, which also rejects cyclic snapshots
+func (s *snapshot[T]) restore() (soydepend.Graph[T], error) {
+	g := soydepend.New[T]()
+	g.AddNodes(s.Nodes...)
+
+	for _, edge := range s.Edges {
+		err := g.Depend(edge.Dependent, edge.Dependency, edge.Kind)
+		if err != nil {
+			return g, fmt.Errorf("edge %v -> %v: %w", edge.Dependent, edge.Dependency, err)
+		}
+	}
+
+	for _, node := range s.Explicit {
+		err := g.SetInstallReason(node, soydepend.InstallExplicit)
+		if err != nil {
+			return g, fmt.Errorf("explicit node %v: %w", node, err)
+		}
+	}
+
+	for _, node := range s.Pinned {
+		err := g.Pin(node)
+		if err != nil {
+			return g, fmt.Errorf("pinned node %v: %w", node, err)
+		}
+	}
+
+	return g, nil
+}
+
+// readSnapshot reads snapshot at path, returning an empty snapshot if there is no such file
+func readSnapshot[T comparable](path string) (*snapshot[T], error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &snapshot[T]{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(snapshot[T])
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	return s, nil
+}
+
+// writeSnapshot atomically replaces snapshot at path, by writing to a temporary file
+// and renaming it over the old snapshot only after it is synced to disk
+func writeSnapshot[T comparable](path string, s *snapshot[T]) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes renames and file creations in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if errClose := d.Close(); err == nil {
+		err = errClose
+	}
+
+	return err
+}
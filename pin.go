@@ -0,0 +1,67 @@
+package soydepend
+
+import "fmt"
+
+// PinPolicy controls how TryRemoveForce, TryRemoveAutoRemove and removal plans
+// treat pinned dependents reached by the removal cascade.
+// Pinned dependencies orphaned by TryRemoveAutoRemove are always kept
+// like InstallExplicit nodes, regardless of PinPolicy.
+type PinPolicy uint8
+
+const (
+	PinRefuse PinPolicy = iota // Refuse the whole removal with *PinnedError[T] (default)
+	PinStop                    // Stop the cascade at pinned nodes, which only lose their edges to removed nodes
+)
+
+func (p PinPolicy) String() string {
+	switch p {
+	case PinRefuse:
+		return "refuse"
+	case PinStop:
+		return "stop"
+	}
+
+	return fmt.Sprintf("PinPolicy(%d)", p)
+}
+
+// Pin protects node from removal.
+// It returns ErrNoSuchNode if node is not in g.
+func (g *Graph[T]) Pin(node T) error {
+	if !g.store.HasNode(node) {
+		return ErrNoSuchNode
+	}
+
+	g.pinned[node] = struct{}{}
+
+	return nil
+}
+
+// Unpin removes the protection set by Pin
+func (g *Graph[T]) Unpin(node T) {
+	delete(g.pinned, node)
+}
+
+func (g *Graph[T]) IsPinned(node T) bool { return g.pinned.Contains(node) } // Returns whether node is pinned
+func (g *Graph[T]) Pinned() Set[T]       { return copyMap(g.pinned) }       // Returns a copy of pinned nodes
+
+func (g *Graph[T]) SetPinPolicy(policy PinPolicy) { g.pinPolicy = policy } // Sets how removal treats pinned nodes
+func (g *Graph[T]) PinPolicy() PinPolicy          { return g.pinPolicy }   // Returns how removal treats pinned nodes
+
+// checkPinned returns *PinnedError[T] if target is pinned, or if pin policy
+// is PinRefuse and target has pinned deep dependents via edges of kinds in mask.
+func (g *Graph[T]) checkPinned(target T, mask EdgeKind) error {
+	if g.pinned.Contains(target) {
+		return &PinnedError[T]{Node: target, Chain: []T{target}}
+	}
+
+	if g.pinPolicy != PinRefuse || len(g.pinned) == 0 {
+		return nil
+	}
+
+	chain := g.pathDeep(g.store.RangeDependents, target, mask, g.pinned.Contains)
+	if chain != nil {
+		return &PinnedError[T]{Node: chain[len(chain)-1], Chain: chain}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestPinRefuse(t *testing.T) {
+	g := initTestGraph(t)
+
+	err := g.Pin("z")
+	if !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatal("expecting ErrNoSuchNode, got", err)
+	}
+
+	err = g.Pin("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !g.IsPinned("d") || !reflect.DeepEqual(g.Pinned(), soydepend.NodeSet("d")) {
+		t.Fatal("d should be pinned")
+	}
+
+	before := g.Clone()
+	for _, remove := range []func(string, ...soydepend.EdgeKind) error{g.TryRemoveForce, g.TryRemoveAutoRemove} {
+		err = remove("a")
+		if !errors.Is(err, soydepend.ErrPinned) {
+			t.Fatal("expecting ErrPinned, got", err)
+		}
+
+		var errPinned *soydepend.PinnedError[string]
+		if !errors.As(err, &errPinned) {
+			t.Fatalf("expecting PinnedError, got %T", err)
+		}
+
+		if errPinned.Node != "d" || !reflect.DeepEqual(errPinned.Chain, []string{"a", "c", "d"}) {
+			t.Fatalf("unexpected pinned error: %+v", errPinned)
+		}
+
+		g.AssertRelationships()
+		if !reflect.DeepEqual(g.GraphDependencies(), before.GraphDependencies()) {
+			t.Fatal("graph changed after refused removal")
+		}
+	}
+
+	_, err = g.PlanRemoveForce("c")
+	if !errors.Is(err, soydepend.ErrPinned) {
+		t.Fatal("expecting ErrPinned from plan, got", err)
+	}
+
+	// Pinned targets are always refused
+	err = g.Remove("d")
+	if !errors.Is(err, soydepend.ErrPinned) {
+		t.Fatal("expecting ErrPinned, got", err)
+	}
+
+	// Legacy removal silently refuses to remove pinned nodes
+	for _, remove := range []func(string, ...soydepend.EdgeKind){g.RemoveForce, g.RemoveAutoRemove} {
+		remove("a")
+		remove("d")
+		g.AssertRelationships()
+		if !reflect.DeepEqual(g.GraphDependencies(), before.GraphDependencies()) {
+			t.Fatal("graph changed after refused legacy removal")
+		}
+	}
+
+	// Legacy removal stops at pinned nodes with PinStop
+	g.SetPinPolicy(soydepend.PinStop)
+	g.RemoveForce("a")
+	g.RemoveForce("d")
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("a", "b", "c"))
+	if !g.Contains("d") || len(g.DependenciesDirect("d")) != 0 {
+		t.Fatal("pinned d should be kept without edges")
+	}
+
+	g.Unpin("d")
+	err = g.Remove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertNotContains(t, g, soydepend.NodeSet("d"))
+
+	// Pinned orphans are kept without refusing the removal
+	err = g.Pin("x")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.SetPinPolicy(soydepend.PinRefuse)
+	err = g.TryRemoveAutoRemove("y")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("y"))
+	if !g.Contains("x") {
+		t.Fatal("pinned orphan x should be kept")
+	}
+}
+
+func TestPinStop(t *testing.T) {
+	g := initTestGraph(t)
+	g.SetPinPolicy(soydepend.PinStop)
+
+	err := g.Pin("c")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	plan, err := g.PlanRemoveForce("a")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if removes := plan.Removes(); !reflect.DeepEqual(removes, soydepend.NodeSet("a", "b")) {
+		t.Fatal("unexpected planned removal", removes)
+	}
+
+	err = g.TryRemoveForce("a")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("a", "b"))
+	if !g.DependsOnDirectly("d", "c") {
+		t.Fatal("cascade should stop at pinned c")
+	}
+
+	// Pinned orphan is kept
+	err = g.TryRemoveAutoRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("d"))
+	if !g.Contains("c") {
+		t.Fatal("pinned orphan c should be kept")
+	}
+
+	if orphans := g.Orphans(); orphans.Contains("c") {
+		t.Fatal("pinned c should not be an orphan")
+	}
+}
+
+func TestApplyPlanPinned(t *testing.T) {
+	g := initTestGraph(t)
+
+	plan, err := g.PlanRemoveAutoRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.Pin("c")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.ApplyPlan(plan)
+	if !errors.Is(err, soydepend.ErrPinned) {
+		t.Fatal("expecting ErrPinned, got", err)
+	}
+
+	assertContainsAll(t, g, soydepend.NodeSet("c", "d"))
+}
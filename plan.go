@@ -0,0 +1,219 @@
+package soydepend
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrStalePlan = errors.New("stale removal plan")
+
+// RemovalReason tells why a node is included in a RemovalPlan
+type RemovalReason uint8
+
+const (
+	ReasonTarget    RemovalReason = iota + 1 // Node is the removal target
+	ReasonDependent                          // Node depends on Cause, which is being removed
+	ReasonOrphan                             // Node is a dependency left without dependents after Cause is removed
+)
+
+// Edge is a dependent->dependency edge
+type Edge[T comparable] struct {
+	Dependent  T
+	Dependency T
+}
+
+// RemovedNode is a node to be removed by a RemovalPlan
+type RemovedNode[T comparable] struct {
+	Node   T
+	Reason RemovalReason
+	Cause  T // Node that caused this node to be removed, zero value if Reason is ReasonTarget
+}
+
+// RemovalPlan lists what a removal would remove from graph, in removal order.
+// Plans are computed by PlanRemove, PlanRemoveForce and PlanRemoveAutoRemove
+// without modifying the graph, and can be executed later with ApplyPlan.
+type RemovalPlan[T comparable] struct {
+	Target T
+	Nodes  []RemovedNode[T] // Nodes to be removed
+	Edges  []Edge[T]        // Edges to be broken
+}
+
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonTarget:
+		return "target"
+	case ReasonDependent:
+		return "dependent"
+	case ReasonOrphan:
+		return "orphan"
+	}
+
+	return fmt.Sprintf("RemovalReason(%d)", r)
+}
+
+func (n RemovedNode[T]) String() string {
+	switch n.Reason {
+	case ReasonDependent:
+		return fmt.Sprintf("%v (dependent of %v)", n.Node, n.Cause)
+	case ReasonOrphan:
+		return fmt.Sprintf("%v (orphaned dependency of %v)", n.Node, n.Cause)
+	}
+
+	return fmt.Sprintf("%v (%s)", n.Node, n.Reason.String())
+}
+
+// Removes returns the set of nodes to be removed by the plan
+func (p *RemovalPlan[T]) Removes() Set[T] {
+	removes := make(Set[T])
+	for i := range p.Nodes {
+		removes[p.Nodes[i].Node] = struct{}{}
+	}
+
+	return removes
+}
+
+// PlanRemove returns what Remove would remove, without modifying g.
+// Like Remove, it returns ErrDependentExists if target has dependents,
+// and ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) PlanRemove(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	err := checkKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := g.Clone()
+	plan := &RemovalPlan[T]{Target: target}
+
+	err = clone.remove(target, kindMask(kinds, KindAll), plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// PlanRemoveForce returns what TryRemoveForce would remove, without modifying g.
+// It returns *IntegrityError[T] if g is found to be corrupted,
+// and *PinnedError[T] if the removal is refused because of pinned nodes.
+// Like TryRemoveForce, it returns ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) PlanRemoveForce(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	err := checkKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := kindMask(kinds, KindAll)
+
+	err = g.checkPinned(target, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := g.Clone()
+	plan := &RemovalPlan[T]{Target: target}
+
+	err = clone.removeCascade(target, false, mask, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// PlanRemoveAutoRemove returns what TryRemoveAutoRemove would remove, without modifying g.
+// It returns *IntegrityError[T] if g is found to be corrupted,
+// and *PinnedError[T] if the removal is refused because of pinned nodes.
+// Like TryRemoveAutoRemove, it returns ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) PlanRemoveAutoRemove(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	err := checkKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := kindMask(kinds, KindAll)
+
+	err = g.checkPinned(target, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := g.Clone()
+	plan := &RemovalPlan[T]{Target: target}
+
+	err = clone.removeCascade(target, true, mask, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ApplyPlan executes plan on g. If g was modified after the plan was made such that
+// the plan would leave dangling references, ApplyPlan returns ErrStalePlan
+// and leaves g untouched. Likewise, it returns *PinnedError[T] if a planned node
+// has been pinned since.
+func (g *Graph[T]) ApplyPlan(plan *RemovalPlan[T]) error {
+	edges := make(map[Edge[T]]struct{}, len(plan.Edges))
+	for _, edge := range plan.Edges {
+		if !g.DependsOnDirectly(edge.Dependent, edge.Dependency) {
+			return fmt.Errorf("%w: no edge %v -> %v", ErrStalePlan, edge.Dependent, edge.Dependency)
+		}
+
+		edges[edge] = struct{}{}
+	}
+
+	for i := range plan.Nodes {
+		node := plan.Nodes[i].Node
+		if !g.store.HasNode(node) {
+			return fmt.Errorf("%w: no node %v", ErrStalePlan, node)
+		}
+
+		if g.pinned.Contains(node) {
+			return &PinnedError[T]{Node: node, Chain: []T{node}}
+		}
+
+		var unplanned *Edge[T]
+		g.store.RangeDependents(node, func(dependent T, _ EdgeKind) bool {
+			if edge := (Edge[T]{Dependent: dependent, Dependency: node}); !contains(edges, edge) {
+				unplanned = &edge
+			}
+
+			return unplanned == nil
+		})
+
+		g.store.RangeDependencies(node, func(dependency T, _ EdgeKind) bool {
+			if edge := (Edge[T]{Dependent: node, Dependency: dependency}); unplanned == nil && !contains(edges, edge) {
+				unplanned = &edge
+			}
+
+			return unplanned == nil
+		})
+
+		if unplanned != nil {
+			return fmt.Errorf("%w: unplanned edge %v -> %v", ErrStalePlan, unplanned.Dependent, unplanned.Dependency)
+		}
+	}
+
+	// All edges of planned nodes are planned edges, so deleting nodes breaks exactly the planned edges
+	for i := range plan.Nodes {
+		g.Delete(plan.Nodes[i].Node)
+	}
+
+	return nil
+}
+
+func (p *RemovalPlan[T]) addNode(node RemovedNode[T]) {
+	if p == nil {
+		return
+	}
+
+	p.Nodes = append(p.Nodes, node)
+}
+
+func (p *RemovalPlan[T]) addEdge(dependent, dependency T) {
+	if p == nil {
+		return
+	}
+
+	p.Edges = append(p.Edges, Edge[T]{Dependent: dependent, Dependency: dependency})
+}
@@ -0,0 +1,160 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestPlanRemove(t *testing.T) {
+	g := initTestGraph(t)
+
+	_, err := g.PlanRemove("a")
+	if !errors.Is(err, soydepend.ErrDependentExists) {
+		t.Fatal("expecting ErrDependentExists, got", err)
+	}
+
+	plan, err := g.PlanRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertPlan(t, plan, map[string]soydepend.RemovedNode[string]{
+		"d": {Node: "d", Reason: soydepend.ReasonTarget},
+	}, []soydepend.Edge[string]{
+		{Dependent: "d", Dependency: "c"},
+	})
+
+	assertContainsAll(t, g, soydepend.NodeSet("d"))
+	assertApplyPlan(t, g, plan)
+}
+
+func TestPlanRemoveForce(t *testing.T) {
+	g := initTestGraph(t)
+
+	plan, err := g.PlanRemoveForce("a")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertPlan(t, plan, map[string]soydepend.RemovedNode[string]{
+		"a": {Node: "a", Reason: soydepend.ReasonTarget},
+		"b": {Node: "b", Reason: soydepend.ReasonDependent, Cause: "a"},
+		"c": {Node: "c", Reason: soydepend.ReasonDependent, Cause: "a"},
+		"d": {Node: "d", Reason: soydepend.ReasonDependent, Cause: "c"},
+	}, []soydepend.Edge[string]{
+		{Dependent: "b", Dependency: "a"},
+		{Dependent: "c", Dependency: "a"},
+		{Dependent: "d", Dependency: "c"},
+	})
+
+	assertContainsAll(t, g, soydepend.NodeSet("a", "b", "c", "d"))
+	assertApplyPlan(t, g, plan)
+}
+
+func TestPlanRemoveAutoRemove(t *testing.T) {
+	g := initTestGraph(t)
+
+	plan, err := g.PlanRemoveAutoRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertPlan(t, plan, map[string]soydepend.RemovedNode[string]{
+		"d": {Node: "d", Reason: soydepend.ReasonTarget},
+		"c": {Node: "c", Reason: soydepend.ReasonOrphan, Cause: "d"},
+	}, []soydepend.Edge[string]{
+		{Dependent: "d", Dependency: "c"},
+		{Dependent: "c", Dependency: "a"},
+	})
+
+	assertContainsAll(t, g, soydepend.NodeSet("a", "c", "d"))
+
+	// Plan must match what RemoveAutoRemove actually removes
+	removed := g.Clone()
+	removed.RemoveAutoRemove("d")
+	assertApplyPlan(t, g, plan)
+
+	if !reflect.DeepEqual(g.GraphNodes(), removed.GraphNodes()) {
+		t.Fatalf("plan removes differently from RemoveAutoRemove: %v vs %v", g.GraphNodes(), removed.GraphNodes())
+	}
+}
+
+func TestApplyStalePlan(t *testing.T) {
+	g := initTestGraph(t)
+
+	plan, err := g.PlanRemoveForce("c")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.Depend("z", "d")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = g.ApplyPlan(plan)
+	if !errors.Is(err, soydepend.ErrStalePlan) {
+		t.Fatal("expecting ErrStalePlan, got", err)
+	}
+
+	g.AssertRelationships()
+	assertContainsAll(t, g, soydepend.NodeSet("c", "d", "z"))
+
+	g.Delete("d")
+	err = g.ApplyPlan(plan)
+	if !errors.Is(err, soydepend.ErrStalePlan) {
+		t.Fatal("expecting ErrStalePlan, got", err)
+	}
+}
+
+func assertPlan(
+	t *testing.T,
+	plan *soydepend.RemovalPlan[string],
+	expectedNodes map[string]soydepend.RemovedNode[string],
+	expectedEdges []soydepend.Edge[string],
+) {
+	if len(plan.Nodes) != len(expectedNodes) {
+		t.Fatalf("expecting %d nodes, got %v", len(expectedNodes), plan.Nodes)
+	}
+
+	if plan.Nodes[0].Reason != soydepend.ReasonTarget {
+		t.Fatal("first planned node should be target, got", plan.Nodes[0])
+	}
+
+	for _, node := range plan.Nodes {
+		if expected := expectedNodes[node.Node]; node != expected {
+			t.Fatalf("unexpected planned node: expecting %v, got %v", expected, node)
+		}
+	}
+
+	if len(plan.Edges) != len(expectedEdges) {
+		t.Fatalf("expecting %d edges, got %v", len(expectedEdges), plan.Edges)
+	}
+
+	for _, expected := range expectedEdges {
+		found := false
+		for _, edge := range plan.Edges {
+			if edge == expected {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Fatalf("missing planned edge %v in %v", expected, plan.Edges)
+		}
+	}
+}
+
+func assertApplyPlan(t *testing.T, g soydepend.Graph[string], plan *soydepend.RemovalPlan[string]) {
+	err := g.ApplyPlan(plan)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertNotContains(t, g, plan.Removes())
+}
@@ -0,0 +1,127 @@
+package soydepend
+
+// MarkDirty marks node as changed, so that it and its dependents are rebuilt.
+// It returns ErrNoSuchNode if node is not in g.
+func (g *Graph[T]) MarkDirty(node T) error {
+	if !g.store.HasNode(node) {
+		return ErrNoSuchNode
+	}
+
+	g.dirty[node] = struct{}{}
+
+	return nil
+}
+
+// MarkClean removes the mark set by MarkDirty
+func (g *Graph[T]) MarkClean(node T) {
+	delete(g.dirty, node)
+}
+
+func (g *Graph[T]) IsDirty(node T) bool { return g.dirty.Contains(node) } // Returns whether node is marked dirty
+func (g *Graph[T]) Dirty() Set[T]       { return copyMap(g.dirty) }       // Returns a copy of nodes marked dirty
+
+// DirtySet returns the minimal set of nodes to be rebuilt,
+// i.e. nodes marked dirty and all of their deep dependents.
+//
+// If kinds are given, only dependents via edges with any of the kinds are included.
+// It returns nil if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) DirtySet(kinds ...EdgeKind) Set[T] {
+	if checkKinds(kinds) != nil {
+		return nil
+	}
+
+	set := copyMap(g.dirty)
+	for node := range g.dirty {
+		for dependent := range g.Dependents(node, kinds...) {
+			set[dependent] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// RebuildOrder returns DirtySet in layers of topological order, like Layers.
+// Nodes in a layer only depend on nodes in previous layers.
+// It returns nil if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) RebuildOrder(kinds ...EdgeKind) []Set[T] {
+	set := g.DirtySet(kinds...)
+
+	var order []Set[T]
+	for _, layer := range g.Layers(kinds...) {
+		if len(set) == 0 {
+			break
+		}
+
+		rebuild := make(Set[T])
+		for node := range layer {
+			if set.Contains(node) {
+				rebuild[node] = struct{}{}
+				delete(set, node)
+			}
+		}
+
+		if len(rebuild) != 0 {
+			order = append(order, rebuild)
+		}
+	}
+
+	return order
+}
+
+// Rebuild calls build on nodes in RebuildOrder, and marks rebuilt nodes clean.
+// build reports whether the node output changed. If it did not, the node's dependents
+// are not rebuilt because of it (early cutoff), and are skipped unless another
+// rebuilt dependency changed or they are marked dirty themselves.
+//
+// Rebuild returns the set of rebuilt nodes. If build fails, Rebuild stops and returns
+// *NodeError[T]. Nodes that still had to be rebuilt are then marked dirty,
+// so that the next Rebuild picks up where this one left off.
+//
+// It returns ErrInvalidEdgeKind without rebuilding anything if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) Rebuild(build func(node T) (changed bool, err error), kinds ...EdgeKind) (Set[T], error) {
+	err := checkKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := kindMask(kinds, KindAll)
+	order := g.RebuildOrder(kinds...)
+
+	stale := Set[T](copyMap(g.dirty)) // Nodes to be rebuilt
+	rebuilt := make(Set[T])
+
+	for _, layer := range order {
+		for node := range layer {
+			if !stale.Contains(node) {
+				continue
+			}
+
+			changed, err := build(node)
+			if err != nil {
+				for node := range stale {
+					g.dirty[node] = struct{}{}
+				}
+
+				return rebuilt, &NodeError[T]{Node: node, Err: err}
+			}
+
+			delete(stale, node)
+			delete(g.dirty, node)
+			rebuilt[node] = struct{}{}
+
+			if !changed {
+				continue
+			}
+
+			g.store.RangeDependents(node, func(dependent T, kind EdgeKind) bool {
+				if kind&mask != 0 {
+					stale[dependent] = struct{}{}
+				}
+
+				return true
+			})
+		}
+	}
+
+	return rebuilt, nil
+}
@@ -0,0 +1,142 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestRebuildOrder(t *testing.T) {
+	g := initTestGraph(t)
+
+	err := g.MarkDirty("z")
+	if !errors.Is(err, soydepend.ErrNoSuchNode) {
+		t.Fatal("expecting ErrNoSuchNode, got", err)
+	}
+
+	for _, node := range []string{"c", "x"} {
+		if err := g.MarkDirty(node); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if !g.IsDirty("c") || g.IsDirty("d") {
+		t.Fatal("only marked nodes should be dirty")
+	}
+
+	assertSet(t, g.DirtySet(), soydepend.NodeSet("c", "d", "x", "y"))
+
+	expected := []soydepend.Set[string]{
+		soydepend.NodeSet("x"),
+		soydepend.NodeSet("c", "y"),
+		soydepend.NodeSet("d"),
+	}
+
+	if order := g.RebuildOrder(); !reflect.DeepEqual(order, expected) {
+		t.Fatalf("unexpected rebuild order: expecting %v, got %v", expected, order)
+	}
+
+	g.MarkClean("x")
+	g.Delete("c")
+	g.AssertRelationships()
+
+	if dirty := g.Dirty(); len(dirty) != 0 {
+		t.Fatal("deleted nodes should not be dirty, got", dirty)
+	}
+
+	if order := g.RebuildOrder(); len(order) != 0 {
+		t.Fatal("unexpected rebuild order of clean graph:", order)
+	}
+}
+
+func TestRebuildEarlyCutoff(t *testing.T) {
+	g := initTestGraph(t)
+	if err := g.MarkDirty("a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	var built []string
+	changes := map[string]bool{"a": true}
+
+	rebuild := func(node string) (bool, error) {
+		built = append(built, node)
+		return changes[node], nil
+	}
+
+	rebuilt, err := g.Rebuild(rebuild)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// b and c are unchanged, so d is cut off
+	assertSet(t, rebuilt, soydepend.NodeSet("a", "b", "c"))
+	if built[0] != "a" {
+		t.Fatal("a should be rebuilt first, got", built)
+	}
+
+	if dirty := g.Dirty(); len(dirty) != 0 {
+		t.Fatal("rebuilt nodes should be clean, got", dirty)
+	}
+
+	if err := g.MarkDirty("a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	built = nil
+	changes["c"] = true
+
+	rebuilt, err = g.Rebuild(rebuild)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertSet(t, rebuilt, soydepend.NodeSet("a", "b", "c", "d"))
+	if built[len(built)-1] != "d" {
+		t.Fatal("d should be rebuilt last, got", built)
+	}
+}
+
+func TestRebuildError(t *testing.T) {
+	g := initTestGraph(t)
+	if err := g.MarkDirty("a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	errFoo := errors.New("foo")
+	rebuilt, err := g.Rebuild(func(node string) (bool, error) {
+		if node == "c" {
+			return false, errFoo
+		}
+
+		return true, nil
+	})
+
+	if !errors.Is(err, errFoo) {
+		t.Fatal("expecting errFoo, got", err)
+	}
+
+	var errNode *soydepend.NodeError[string]
+	if !errors.As(err, &errNode) || errNode.Node != "c" {
+		t.Fatalf("expecting NodeError of c, got %v", err)
+	}
+
+	if !rebuilt.Contains("a") || rebuilt.Contains("c") || rebuilt.Contains("d") {
+		t.Fatal("unexpected rebuilt nodes:", rebuilt)
+	}
+
+	if g.IsDirty("a") || !g.IsDirty("c") {
+		t.Fatal("failed nodes should stay dirty, got", g.Dirty())
+	}
+
+	// The next rebuild resumes from c
+	rebuilt, err = g.Rebuild(func(string) (bool, error) { return true, nil })
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !rebuilt.Contains("c") || !rebuilt.Contains("d") || rebuilt.Contains("a") {
+		t.Fatal("unexpected rebuilt nodes:", rebuilt)
+	}
+}
@@ -1,16 +1,6 @@
 package soydepend
 
-import (
-	"errors"
-	"fmt"
-)
-
-var (
-	ErrDependsOnSelf      = errors.New("node depends on self")
-	ErrDependentExists    = errors.New("dependent exists")
-	ErrNoSuchDependency   = errors.New("no such direct dependency")
-	ErrCircularDependency = errors.New("circular dependency")
-)
+import "errors"
 
 type (
 	Set[T comparable]   map[T]struct{} // Set is a set of T nodes, implemented with Go map
@@ -18,16 +8,49 @@ type (
 )
 
 type Graph[T comparable] struct {
-	nodes        Set[T]   // All nodes in Graph
-	dependents   Edges[T] // dependency -> []dependents
-	dependencies Edges[T] // dependent  -> []dependencies
+	store     Store[T]     // Nodes, edges and edge kinds
+	explicit  Set[T]       // Nodes with InstallExplicit reason
+	pinned    Set[T]       // Nodes protected from removal
+	dirty     Set[T]       // Nodes marked dirty for rebuild
+	digests   map[T][]byte // Caller-supplied digests of nodes
+	hashes    map[T]Hash   // Cached Merkle hashes of nodes
+	order     map[T]int    // Position of nodes in topological order, see topo.go
+	nextOrder *int         // Position of the next new node in topological order, shared like maps by copies of Graph
+	headOrder *int         // Position before every node in topological order, shared like nextOrder
+	pinPolicy PinPolicy
+	onDelete  func(T) // Called with every node deleted from Graph, not copied by Clone
 }
 
 func New[T comparable]() Graph[T] {
+	return newGraph[T](newMapStore[T]())
+}
+
+// NewWithStore returns a new graph backed by s.
+//
+// If s already has nodes, they are assigned a topological order from Layers.
+// s must then be acyclic, or Validate reports nodes in cycles as unordered.
+func NewWithStore[T comparable](s Store[T]) Graph[T] {
+	g := newGraph[T](s)
+	for _, layer := range g.Layers() {
+		for node := range layer {
+			g.addOrder(node)
+		}
+	}
+
+	return g
+}
+
+func newGraph[T comparable](s Store[T]) Graph[T] {
 	return Graph[T]{
-		nodes:        make(Set[T]),
-		dependents:   make(Edges[T]),
-		dependencies: make(Edges[T]),
+		store:     s,
+		explicit:  make(Set[T]),
+		pinned:    make(Set[T]),
+		dirty:     make(Set[T]),
+		digests:   make(map[T][]byte),
+		hashes:    make(map[T]Hash),
+		order:     make(map[T]int),
+		nextOrder: new(int),
+		headOrder: new(int),
 	}
 }
 
@@ -68,65 +91,146 @@ func (e Edges[T]) Contains(key, item T) bool {
 }
 
 func (g *Graph[T]) Contains(node T) bool {
-	return contains(g.nodes, node)
+	return g.store.HasNode(node)
 }
 
-func (g *Graph[T]) GraphNodes() Set[T]               { return copyMap(g.nodes) }              // Returns a copy of all nodes
-func (g *Graph[T]) GraphDependents() Edges[T]        { return copyMap(g.dependents) }         // Returns a copy of dependent map
-func (g *Graph[T]) GraphDependencies() Edges[T]      { return copyMap(g.dependencies) }       // Returns a copy of dependency map
-func (g *Graph[T]) DependentsDirect(node T) Set[T]   { return copyMap(g.dependents)[node] }   // Returns a copy of direct dependents of node
-func (g *Graph[T]) DependenciesDirect(node T) Set[T] { return copyMap(g.dependencies)[node] } // Returns a copy of direct dependencies of node
+func (g *Graph[T]) GraphNodes() Set[T]               { return g.nodeSet() }                          // Returns a copy of all nodes
+func (g *Graph[T]) GraphDependents() Edges[T]        { return g.edgeMap(g.store.RangeDependents) }   // Returns a copy of dependent map
+func (g *Graph[T]) GraphDependencies() Edges[T]      { return g.edgeMap(g.store.RangeDependencies) } // Returns a copy of dependency map
+func (g *Graph[T]) DependentsDirect(node T) Set[T]   { return g.directDependents(node) }             // Returns a copy of direct dependents of node
+func (g *Graph[T]) DependenciesDirect(node T) Set[T] { return g.directDependencies(node) }           // Returns a copy of direct dependencies of node
 
 func (g *Graph[T]) Clone() Graph[T] {
+	nextOrder, headOrder := *g.nextOrder, *g.headOrder
+
 	return Graph[T]{
-		nodes:        copyMap(g.nodes),
-		dependencies: copyDep(g.dependencies),
-		dependents:   copyDep(g.dependents),
+		store:     g.store.Clone(),
+		explicit:  copyMap(g.explicit),
+		pinned:    copyMap(g.pinned),
+		dirty:     copyMap(g.dirty),
+		pinPolicy: g.pinPolicy,
+		digests:   copyMap(g.digests),
+		hashes:    copyMap(g.hashes),
+		order:     copyMap(g.order),
+		nextOrder: &nextOrder,
+		headOrder: &headOrder,
+	}
+}
+
+// AddNode adds node to g as an isolated node, i.e. a node without any edges.
+// Adding an existing node is a no-op, and its edges are preserved.
+//
+// Isolated nodes are leaves, and appear in the first layer of Layers.
+// They can be removed with any removal methods, since they have no dependents.
+func (g *Graph[T]) AddNode(node T) {
+	g.store.AddNode(node)
+	g.addOrder(node)
+}
+
+// AddNodes adds all nodes to g with AddNode.
+func (g *Graph[T]) AddNodes(nodes ...T) {
+	for i := range nodes {
+		g.AddNode(nodes[i])
 	}
 }
 
 // Depend establishes the dependency relationship between 2 nodes.
 // It errs if a node depends on itself, or if circular dependency is found.
-func (g *Graph[T]) Depend(dependent, dependency T) error {
+// Circular dependency errors are of type *CycleError[T].
+//
+// The edge has the given kinds, or KindRuntime if none is given.
+// Depending on an existing edge adds the kinds to the edge.
+// It returns ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+// Circular dependency is checked regardless of kinds.
+//
+// Cycles are detected with an incrementally maintained topological order,
+// so only nodes ordered between dependency and dependent are searched.
+func (g *Graph[T]) Depend(dependent, dependency T, kinds ...EdgeKind) error {
 	if dependent == dependency {
 		return ErrDependsOnSelf
 	}
 
-	if g.DependsOn(dependency, dependent) {
-		return ErrCircularDependency
+	err := checkKinds(kinds)
+	if err != nil {
+		return err
 	}
 
-	addToDep(g.dependents, dependency, dependent)
-	addToDep(g.dependencies, dependent, dependency)
+	if g.store.HasNode(dependent) && g.store.HasNode(dependency) {
+		if !g.reorder(dependent, dependency) {
+			path := g.pathDeep(g.store.RangeDependencies, dependency, KindAll, equal(dependent))
+			return &CycleError[T]{Path: append(path, dependency)}
+		}
+	} else {
+		// A new node has no edges yet, so the edge cannot create a cycle.
+		// New dependencies go before every node, and new dependents after every node,
+		// so the order already agrees with the edge without any search.
+		if !g.store.HasNode(dependency) {
+			g.addOrderHead(dependency)
+		}
+
+		g.addOrder(dependent)
+	}
 
-	g.nodes[dependency] = struct{}{}
-	g.nodes[dependent] = struct{}{}
+	g.invalidateHash(dependent)
+	g.store.SetEdge(dependent, dependency, g.store.EdgeKind(dependent, dependency)|kindMask(kinds, KindRuntime))
 
 	return nil
 }
 
 // Undepend removes dependent->dependency edges in g.
 // It returns ErrNoSuchDependency if the relationship is indirect.
-func (g *Graph[T]) Undepend(dependent, dependency T) error {
+//
+// Both nodes stay in g after the edge is removed,
+// and either may be left isolated.
+//
+// If kinds are given, only those kinds are removed from the edge,
+// and the edge is only removed once it has no kinds left.
+// It returns ErrNoSuchDependency if the edge has none of the kinds,
+// or ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) Undepend(dependent, dependency T, kinds ...EdgeKind) error {
+	err := checkKinds(kinds)
+	if err != nil {
+		return err
+	}
+
 	if !g.DependsOnDirectly(dependent, dependency) {
 		return ErrNoSuchDependency
 	}
 
-	removeFromDep(g.dependents, dependency, dependent)
-	removeFromDep(g.dependencies, dependent, dependency)
+	if len(kinds) != 0 {
+		mask := kindMask(kinds, KindAll)
+		current := g.store.EdgeKind(dependent, dependency)
+		if current&mask == 0 {
+			return ErrNoSuchDependency
+		}
+
+		if remaining := current &^ mask; remaining != 0 {
+			g.invalidateHash(dependent)
+			g.store.SetEdge(dependent, dependency, remaining)
+			return nil
+		}
+	}
+
+	g.invalidateHash(dependent)
+	g.store.RemoveEdge(dependent, dependency)
 
 	return nil
 }
 
 // DependsOn checks if all deep dependencies of dependent contain dependency
 func (g *Graph[T]) DependsOn(dependent, dependency T) bool {
+	// Deep dependencies always come before dependent in topological order
+	if g.order[dependency] >= g.order[dependent] {
+		return false
+	}
+
 	return g.Dependencies(dependent).Contains(dependency)
 }
 
 // DependsOnDirectly returns a boolean indicating
 // if dependency is a direct dependency of dependent.
 func (g *Graph[T]) DependsOnDirectly(dependent, dependency T) bool {
-	return g.dependencies.Contains(dependent, dependency)
+	return g.store.EdgeKind(dependent, dependency) != 0
 }
 
 // Leaves returns leave nodes,
@@ -134,29 +238,52 @@ func (g *Graph[T]) DependsOnDirectly(dependent, dependency T) bool {
 func (g *Graph[T]) Leaves() Set[T] {
 	leaves := make(Set[T])
 
-	for node := range g.nodes {
-		if len(g.dependencies[node]) != 0 {
-			continue
+	g.store.RangeNodes(func(node T) bool {
+		if g.store.NumDependencies(node) == 0 {
+			leaves[node] = struct{}{}
 		}
 
-		leaves[node] = struct{}{}
-	}
+		return true
+	})
 
 	return leaves
 }
 
-// Dependencies returns all deep dependencies
-func (g *Graph[T]) Dependencies(node T) Set[T] {
-	return g.digDeep(g.dependencies, node)
+// Dependencies returns all deep dependencies.
+// If kinds are given, only edges with any of the kinds are followed.
+// It returns nil if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) Dependencies(node T, kinds ...EdgeKind) Set[T] {
+	if checkKinds(kinds) != nil {
+		return nil
+	}
+
+	mask := kindMask(kinds, KindAll)
+	if closure, ok := g.store.(ClosureStore[T]); ok {
+		return closure.DependencyClosure(node, mask)
+	}
+
+	return g.digDeep(g.store.RangeDependencies, node, mask)
 }
 
-// Dependencies returns all deep dependencies
-func (g *Graph[T]) Dependents(node T) Set[T] {
-	return g.digDeep(g.dependents, node)
+// Dependents returns all deep dependents.
+// If kinds are given, only edges with any of the kinds are followed.
+// It returns nil if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) Dependents(node T, kinds ...EdgeKind) Set[T] {
+	if checkKinds(kinds) != nil {
+		return nil
+	}
+
+	mask := kindMask(kinds, KindAll)
+	if closure, ok := g.store.(ClosureStore[T]); ok {
+		return closure.DependentClosure(node, mask)
+	}
+
+	return g.digDeep(g.store.RangeDependents, node, mask)
 }
 
-func (g *Graph[T]) digDeep(edges Edges[T], node T) Set[T] {
-	if !g.nodes.Contains(node) {
+// digDeep walks edges from node, following only edges with kinds in mask
+func (g *Graph[T]) digDeep(edges rangeFunc[T], node T, mask EdgeKind) Set[T] {
+	if !g.store.HasNode(node) {
 		return nil
 	}
 
@@ -166,19 +293,16 @@ func (g *Graph[T]) digDeep(edges Edges[T], node T) Set[T] {
 	for len(searchNext) != 0 {
 		var discovered []T
 		for _, next := range searchNext {
-			edgeNodes, ok := edges[next]
-			if !ok {
-				continue
-			}
-
-			for edgeNode := range edgeNodes {
-				if results.Contains(edgeNode) {
-					continue
+			edges(next, func(edgeNode T, kind EdgeKind) bool {
+				if kind&mask == 0 || results.Contains(edgeNode) {
+					return true
 				}
 
 				results[edgeNode] = struct{}{}
 				discovered = append(discovered, edgeNode)
-			}
+
+				return true
+			})
 		}
 
 		searchNext = discovered
@@ -187,21 +311,124 @@ func (g *Graph[T]) digDeep(edges Edges[T], node T) Set[T] {
 	return results
 }
 
+// pathDeep is like digDeep, but it also tracks how each node was discovered.
+// It returns the shortest path from src to the first node matched by isDst following edges,
+// or nil if no such node is reachable from src.
+func (g *Graph[T]) pathDeep(edges rangeFunc[T], src T, mask EdgeKind, isDst func(T) bool) []T {
+	if !g.store.HasNode(src) {
+		return nil
+	}
+
+	parents := make(map[T]T)
+	searchNext := []T{src}
+
+	for len(searchNext) != 0 {
+		var discovered []T
+		var dst *T
+
+		for _, next := range searchNext {
+			edges(next, func(edgeNode T, kind EdgeKind) bool {
+				if edgeNode == src || contains(parents, edgeNode) || kind&mask == 0 {
+					return true
+				}
+
+				parents[edgeNode] = next
+				if isDst(edgeNode) {
+					dst = &edgeNode
+					return false
+				}
+
+				discovered = append(discovered, edgeNode)
+
+				return true
+			})
+
+			if dst == nil {
+				continue
+			}
+
+			// Walk back from dst to src
+			path := []T{*dst}
+			for node := *dst; node != src; {
+				node = parents[node]
+				path = append(path, node)
+			}
+
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+
+			return path
+		}
+
+		searchNext = discovered
+	}
+
+	return nil
+}
+
 // Layers returns nodes in topological sort order.
 // Nodes in each outer slot only depend on prior slots,
 // i.e. independent nodes come before dependent ones.
-func (g *Graph[T]) Layers() []Set[T] {
+//
+// If kinds are given, only edges with any of the kinds are considered.
+// It returns nil if any of kinds is 0 or not within KindAll.
+//
+// Layers runs in time linear to the number of nodes and edges, without modifying or cloning g.
+func (g *Graph[T]) Layers(kinds ...EdgeKind) []Set[T] {
+	if checkKinds(kinds) != nil {
+		return nil
+	}
+
+	mask := kindMask(kinds, KindAll)
+
+	// Kahn's algorithm: count unvisited dependencies of each node,
+	// and peel off nodes whose count drops to zero layer by layer
+	pending := make(map[T]int, g.store.Len())
+	current := make(Set[T])
+
+	g.store.RangeNodes(func(node T) bool {
+		n := g.store.NumDependencies(node)
+		if mask != KindAll {
+			n = 0
+			g.store.RangeDependencies(node, func(_ T, kind EdgeKind) bool {
+				if kind&mask != 0 {
+					n++
+				}
+
+				return true
+			})
+		}
+
+		pending[node] = n
+		if n == 0 {
+			current[node] = struct{}{}
+		}
+
+		return true
+	})
+
 	var layers []Set[T]
-	copied := g.Clone()
+	for len(current) != 0 {
+		layers = append(layers, current)
+		next := make(Set[T])
+
+		for node := range current {
+			g.store.RangeDependents(node, func(dependent T, kind EdgeKind) bool {
+				if kind&mask == 0 {
+					return true
+				}
 
-	for len(copied.nodes) != 0 {
-		leaves := copied.Leaves()
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next[dependent] = struct{}{}
+				}
 
-		for leaf := range leaves {
-			copied.Delete(leaf)
+				return true
+			})
 		}
 
-		layers = append(layers, copyMap(leaves))
+		current = next
 	}
 
 	return layers
@@ -209,84 +436,212 @@ func (g *Graph[T]) Layers() []Set[T] {
 
 // RemoveAutoRemove removes target node as well as its dependents and dependencies,
 // like with pacman -Rns, or APT autoremove commands.
-func (g *Graph[T]) RemoveAutoRemove(target T) {
-	queue := []T{target}
+//
+// Only dependencies whose last dependent is removed by this call are removed.
+// Nodes that were already isolated before the call, as well as dependencies
+// with InstallExplicit reason or pinned, are left alone.
+//
+// If kinds are given, only edges with any of the kinds are followed,
+// i.e. dependents via other edges only lose their edges to removed nodes,
+// and dependencies are removed once they have no dependents via edges of the kinds.
+//
+// Pinned nodes are never removed, and PinPolicy is honoured like with TryRemoveAutoRemove,
+// except that refused removals are silent: nothing is removed if target is pinned,
+// or if PinPolicy is PinRefuse and the removal would reach a pinned dependent.
+// Use TryRemoveAutoRemove to tell whether the removal was refused.
+// Likewise, nothing is removed if any of kinds is 0 or not within KindAll.
+//
+// It panics if g is found to be corrupted. Use TryRemoveAutoRemove
+// to get the corruption as an error instead.
+func (g *Graph[T]) RemoveAutoRemove(target T, kinds ...EdgeKind) {
+	err := g.TryRemoveAutoRemove(target, kinds...)
+	if err != nil && !errors.Is(err, ErrPinned) && !errors.Is(err, ErrInvalidEdgeKind) {
+		panic(err)
+	}
+}
 
-	for len(queue) != 0 {
-		current := popQueue(&queue)
+// TryRemoveAutoRemove is like RemoveAutoRemove, but returns *IntegrityError[T]
+// instead of panicking if g is found to be corrupted during the removal.
+// The removal stops at the first invalid reference found, and g may be partially modified.
+//
+// It returns *PinnedError[T] if target is pinned, or if PinPolicy is PinRefuse
+// and the removal would reach a pinned dependent. g is untouched in both cases,
+// and when it returns ErrInvalidEdgeKind because any of kinds is 0 or not within KindAll.
+// Pinned dependencies are kept as they are orphaned, and never cause a refusal.
+func (g *Graph[T]) TryRemoveAutoRemove(target T, kinds ...EdgeKind) error {
+	err := checkKinds(kinds)
+	if err != nil {
+		return err
+	}
 
-		for dependent := range g.dependents[current] {
-			err := g.Undepend(dependent, current)
-			if err != nil {
-				panic("bug autoremove undepend dependent->current")
-			}
+	mask := kindMask(kinds, KindAll)
 
-			queue = append(queue, dependent)
-		}
+	err = g.checkPinned(target, mask)
+	if err != nil {
+		return err
+	}
 
-		for dependency := range g.dependencies[current] {
-			siblings, ok := g.dependents[dependency]
-			if !ok {
-				panic("bug")
-			}
+	return g.removeCascade(target, true, mask, nil)
+}
 
-			err := g.Undepend(current, dependency)
-			if err != nil {
-				panic("bug autoremove undepend current->dependency")
-			}
+// Remove removes target including its dependents
+//
+// If kinds are given, only dependents via edges with any of the kinds are removed,
+// while other dependents only lose their edges to removed nodes.
+//
+// Pinned nodes are never removed, and PinPolicy is honoured like with TryRemoveForce,
+// except that refused removals are silent: nothing is removed if target is pinned,
+// or if PinPolicy is PinRefuse and the removal would reach a pinned node.
+// Use TryRemoveForce to tell whether the removal was refused.
+// Likewise, nothing is removed if any of kinds is 0 or not within KindAll.
+//
+// It panics if g is found to be corrupted. Use TryRemoveForce
+// to get the corruption as an error instead.
+func (g *Graph[T]) RemoveForce(target T, kinds ...EdgeKind) {
+	err := g.TryRemoveForce(target, kinds...)
+	if err != nil && !errors.Is(err, ErrPinned) && !errors.Is(err, ErrInvalidEdgeKind) {
+		panic(err)
+	}
+}
 
-			// Check if current is the only dependent node on this dependency
-			// If so, we can safely remove this dependency from the graph
-			_, ok = siblings[current]
-			if len(siblings) == 1 && ok {
-				queue = append(queue, dependency)
-			}
-		}
+// TryRemoveForce is like RemoveForce, but returns *IntegrityError[T]
+// instead of panicking if g is found to be corrupted during the removal.
+// The removal stops at the first invalid reference found, and g may be partially modified.
+//
+// It returns *PinnedError[T] if target is pinned, or if PinPolicy is PinRefuse
+// and the removal would reach a pinned node. g is untouched in both cases,
+// and when it returns ErrInvalidEdgeKind because any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) TryRemoveForce(target T, kinds ...EdgeKind) error {
+	err := checkKinds(kinds)
+	if err != nil {
+		return err
+	}
 
-		delete(g.nodes, current)
+	mask := kindMask(kinds, KindAll)
+
+	err = g.checkPinned(target, mask)
+	if err != nil {
+		return err
 	}
+
+	return g.removeCascade(target, false, mask, nil)
 }
 
-// Remove removes target including its dependents
-func (g *Graph[T]) RemoveForce(target T) {
-	queue := []T{target}
+// Remove removes target with 0 dependents.
+// Otherwise it returns ErrDependentExists.
+// It returns *PinnedError[T] if target is pinned.
+//
+// If kinds are given, only dependents via edges with any of the kinds are counted,
+// and edges from other dependents to target are removed along with target.
+// It returns ErrInvalidEdgeKind if any of kinds is 0 or not within KindAll.
+func (g *Graph[T]) Remove(target T, kinds ...EdgeKind) error {
+	err := checkKinds(kinds)
+	if err != nil {
+		return err
+	}
+
+	return g.remove(target, kindMask(kinds, KindAll), nil)
+}
+
+// remove removes target with 0 dependents via edges of kinds in mask,
+// recording the removal to plan if it is not nil
+func (g *Graph[T]) remove(target T, mask EdgeKind, plan *RemovalPlan[T]) error {
+	if g.hasDependents(target, mask) {
+		return ErrDependentExists
+	}
+
+	if g.pinned.Contains(target) {
+		return &PinnedError[T]{Node: target, Chain: []T{target}}
+	}
+
+	if g.store.HasNode(target) {
+		plan.addNode(RemovedNode[T]{Node: target, Reason: ReasonTarget})
+	}
+
+	g.store.RangeDependents(target, func(dependent T, _ EdgeKind) bool {
+		plan.addEdge(dependent, target)
+		return true
+	})
+
+	g.store.RangeDependencies(target, func(dependency T, _ EdgeKind) bool {
+		plan.addEdge(target, dependency)
+		return true
+	})
+
+	g.Delete(target)
+
+	return nil
+}
+
+// removeCascade removes target and its dependents. If autoremove is true,
+// dependencies left without dependents by the removal are also removed.
+// Only edges with kinds in mask are followed, other edges are just broken.
+// Every removed node and broken edge is recorded to plan if it is not nil.
+//
+// The cascade stops at pinned nodes, which only lose their edges to removed nodes.
+func (g *Graph[T]) removeCascade(target T, autoremove bool, mask EdgeKind, plan *RemovalPlan[T]) error {
+	if g.pinned.Contains(target) {
+		return &PinnedError[T]{Node: target, Chain: []T{target}}
+	}
+
+	queue := []RemovedNode[T]{{Node: target, Reason: ReasonTarget}}
 
 	for len(queue) != 0 {
 		current := popQueue(&queue)
+		if g.store.HasNode(current.Node) {
+			plan.addNode(current)
+		}
+
+		for _, dependent := range adjacentSet(g.store.RangeDependents, current.Node).Slice() {
+			kind := g.store.EdgeKind(dependent, current.Node)
+			if kind == 0 {
+				return integrityError(DanglingInverse, "dependents", current.Node, dependent)
+			}
 
-		for dependent := range g.dependents[current] {
-			err := g.Undepend(dependent, current)
+			followed := kind&mask != 0
+
+			err := g.Undepend(dependent, current.Node)
 			if err != nil {
-				panic("bug: remove-force undepend dependent->current")
+				return integrityError(DanglingInverse, "dependents", current.Node, dependent)
 			}
 
-			queue = append(queue, dependent)
+			plan.addEdge(dependent, current.Node)
+			if !followed || g.pinned.Contains(dependent) {
+				continue
+			}
+
+			queue = append(queue, RemovedNode[T]{Node: dependent, Reason: ReasonDependent, Cause: current.Node})
 		}
 
-		for dependency := range g.dependencies[current] {
-			_, ok := g.dependents[dependency]
-			if !ok {
-				panic("bug")
+		for _, dependency := range adjacentSet(g.store.RangeDependencies, current.Node).Slice() {
+			if !g.store.HasNode(dependency) {
+				return integrityError(DanglingNode, "dependencies", current.Node, dependency)
 			}
 
-			err := g.Undepend(current, dependency)
+			followed := g.store.EdgeKind(current.Node, dependency)&mask != 0
+
+			err := g.Undepend(current.Node, dependency)
 			if err != nil {
-				panic("bug: remove-force undepend current->dependency")
+				return integrityError(DanglingInverse, "dependencies", current.Node, dependency)
 			}
-		}
 
-		delete(g.nodes, current)
-	}
-}
+			plan.addEdge(current.Node, dependency)
+			if !autoremove || !followed {
+				continue
+			}
 
-// Remove removes target with 0 dependents.
-// Otherwise it returns ErrDependentExists.
-func (g *Graph[T]) Remove(target T) error {
-	if g.dependents.ContainsKey(target) {
-		return ErrDependentExists
-	}
+			// Check if current was the last dependent node on this dependency
+			// If so, we can safely remove this dependency from the graph,
+			// unless it was explicitly installed or pinned
+			if g.hasDependents(dependency, mask) || g.explicit.Contains(dependency) || g.pinned.Contains(dependency) {
+				continue
+			}
 
-	g.Delete(target)
+			queue = append(queue, RemovedNode[T]{Node: dependency, Reason: ReasonOrphan, Cause: current.Node})
+		}
+
+		g.Delete(current.Node)
+	}
 
 	return nil
 }
@@ -294,66 +649,114 @@ func (g *Graph[T]) Remove(target T) error {
 // Delete removes a node and all of its references
 // without checking for or handling dangling references
 func (g *Graph[T]) Delete(target T) {
-	// Delete all edges to dependencies
-	for dependency := range g.dependencies[target] {
-		removeFromDep(g.dependents, dependency, target)
+	if g.onDelete != nil && g.store.HasNode(target) {
+		g.onDelete(target)
 	}
 
-	// Delete all edges to dependents
-	for dependent := range g.dependents[target] {
-		removeFromDep(g.dependencies, dependent, target)
-	}
+	g.invalidateHash(target)
+	g.store.DeleteNode(target)
 
-	delete(g.nodes, target)
-	delete(g.dependents, target)
-	delete(g.dependencies, target)
+	delete(g.explicit, target)
+	delete(g.pinned, target)
+	delete(g.dirty, target)
+	delete(g.digests, target)
+	delete(g.hashes, target)
+	delete(g.order, target)
 }
 
 // Realloc allocates a new internal maps of g, and drop the old maps,
 // hopefully to reduce memory footprints from map memory leaks
 // after multiple deletion in large maps.
+// Graphs created with NewCompact are compacted, reclaiming space of deleted nodes.
 //
 // This can also be done with Clone.
 func (g *Graph[T]) Realloc() {
-	g.nodes = copyMap(g.nodes)
-	g.dependents = copyDep(g.dependents)
-	g.dependencies = copyDep(g.dependencies)
+	g.store.Compact()
+	g.explicit = copyMap(g.explicit)
+	g.pinned = copyMap(g.pinned)
+	g.dirty = copyMap(g.dirty)
+	g.digests = copyMap(g.digests)
+	g.hashes = copyMap(g.hashes)
+	g.order = copyMap(g.order)
 }
 
-// AssertRelationship asserts that every node has valid references in all fields.
-// Panics if invalid references are found. Currently only used in tests.
-func (g *Graph[T]) AssertRelationships() {
-	for dependency := range g.dependents {
-		if !g.nodes.Contains(dependency) {
-			panic(fmt.Sprintf("dangling dependency: %v", dependency))
+// Validate checks that every node has valid references in all fields.
+// It returns *IntegrityError[T] listing every invalid reference found,
+// or nil if g is valid.
+func (g *Graph[T]) Validate() error {
+	refs := g.store.Validate()
+
+	g.store.RangeNodes(func(node T) bool {
+		if _, ok := g.order[node]; !ok {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingUnordered, Edges: "order", Key: node})
 		}
 
-		for dependent := range g.dependents[dependency] {
-			if !g.nodes.Contains(dependent) {
-				panic(fmt.Sprintf("dangling dependents for parent %v: child: %v", dependency, dependent))
+		g.store.RangeDependencies(node, func(dependency T, _ EdgeKind) bool {
+			if g.order[dependency] >= g.order[node] {
+				refs = append(refs, DanglingReference[T]{Dangling: DanglingOrder, Edges: "dependencies", Key: node, Node: dependency})
 			}
 
-			if !g.dependencies.Contains(dependent, dependency) {
-				panic(fmt.Sprintf("dangling dependents for parent %v: child: %v", dependency, dependent))
-			}
+			return true
+		})
+
+		return true
+	})
+
+	for node := range g.explicit {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "explicit", Key: node})
 		}
 	}
 
-	for dependent := range g.dependencies {
-		if !g.nodes.Contains(dependent) {
-			panic(fmt.Sprintf("dangling dependents: %v", dependent))
+	for node := range g.pinned {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "pinned", Key: node})
 		}
+	}
 
-		for dependency := range g.dependencies[dependent] {
-			if !g.nodes.Contains(dependency) {
-				panic(fmt.Sprintf("dangling child %v parent: %v", dependent, dependency))
-			}
+	for node := range g.dirty {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "dirty", Key: node})
+		}
+	}
 
-			if !g.dependents.Contains(dependency, dependent) {
-				panic(fmt.Sprintf("dangling child %v parent: %v", dependent, dependency))
-			}
+	for node := range g.order {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "order", Key: node})
 		}
 	}
+
+	for node := range g.digests {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "digests", Key: node})
+		}
+	}
+
+	for node := range g.hashes {
+		if !g.store.HasNode(node) {
+			refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: "hashes", Key: node})
+		}
+	}
+
+	if len(refs) != 0 {
+		return &IntegrityError[T]{References: refs}
+	}
+
+	return nil
+}
+
+// AssertRelationship asserts that every node has valid references in all fields.
+// Panics with the error from Validate if invalid references are found.
+// Currently only used in tests.
+func (g *Graph[T]) AssertRelationships() {
+	err := g.Validate()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func equal[T comparable](target T) func(T) bool {
+	return func(node T) bool { return node == target }
 }
 
 func popQueue[T any](p *[]T) T {
@@ -391,36 +794,3 @@ func copyMap[K comparable, V any](m map[K]V) map[K]V {
 
 	return copied
 }
-
-func copyDep[T comparable](deps Edges[T]) Edges[T] {
-	copied := make(Edges[T])
-	for k, v := range deps {
-		copied[k] = copyMap(v)
-	}
-
-	return copied
-}
-
-func addToDep[T comparable](deps Edges[T], key, node T) {
-	nodes := deps[key]
-	if nodes == nil {
-		nodes = make(Set[T])
-		deps[key] = nodes
-	}
-
-	nodes[node] = struct{}{}
-}
-
-func removeFromDep[T comparable](deps Edges[T], key, target T) {
-	nodes := deps[key]
-	if !contains(nodes, target) {
-		return
-	}
-
-	if len(nodes) != 1 {
-		delete(nodes, target)
-		return
-	}
-
-	delete(deps, key)
-}
@@ -0,0 +1,115 @@
+package soydepend
+
+import (
+	"errors"
+	"testing"
+)
+
+func corruptedGraph(t *testing.T) Graph[string] {
+	g := New[string]()
+	for _, edge := range [][2]string{{"b", "a"}, {"c", "b"}, {"d", "c"}} {
+		err := g.Depend(edge[0], edge[1])
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	// Drop c->b from dependencies, but keep b->c in dependents
+	delete(g.store.(*mapStore[string]).dependencies, "c")
+
+	return g
+}
+
+func TestValidate(t *testing.T) {
+	g := corruptedGraph(t)
+	delete(g.store.(*mapStore[string]).nodes, "d")
+
+	err := g.Validate()
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatal("expecting ErrIntegrity, got", err)
+	}
+
+	var errIntegrity *IntegrityError[string]
+	if !errors.As(err, &errIntegrity) {
+		t.Fatalf("expecting IntegrityError, got %T", err)
+	}
+
+	expecteds := map[DanglingReference[string]]bool{
+		{Dangling: DanglingInverse, Edges: "dependents", Key: "b", Node: "c"}: true,
+		{Dangling: DanglingNode, Edges: "dependents", Key: "c", Node: "d"}:    true,
+		{Dangling: DanglingKey, Edges: "dependencies", Key: "d"}:              true,
+		{Dangling: DanglingKey, Edges: "order", Key: "d"}:                     true,
+	}
+
+	if len(errIntegrity.References) != len(expecteds) {
+		t.Fatalf("expecting %d references, got %v", len(expecteds), errIntegrity.References)
+	}
+
+	for _, ref := range errIntegrity.References {
+		if !expecteds[ref] {
+			t.Fatal("unexpected reference", ref)
+		}
+	}
+
+	g = New[string]()
+	if err := g.Validate(); err != nil {
+		t.Fatal("unexpected error from empty graph:", err)
+	}
+}
+
+func TestTryRemoveCorrupted(t *testing.T) {
+	g := corruptedGraph(t)
+	err := g.TryRemoveForce("a")
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatal("expecting ErrIntegrity from TryRemoveForce, got", err)
+	}
+
+	g = corruptedGraph(t)
+	err = g.TryRemoveAutoRemove("d")
+	if err != nil {
+		t.Fatal("unexpected error from removing d:", err)
+	}
+
+	err = g.TryRemoveAutoRemove("b")
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatal("expecting ErrIntegrity from TryRemoveAutoRemove, got", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expecting panic from RemoveForce")
+		}
+	}()
+
+	corrupted := corruptedGraph(t)
+	corrupted.RemoveForce("a")
+}
+
+func TestCompactRealloc(t *testing.T) {
+	g := NewCompact[int]()
+	for i := 1; i < 100; i++ {
+		if err := g.Depend(i, i-1, KindBuild); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for i := 10; i < 100; i++ {
+		g.Delete(i)
+	}
+
+	s := g.store.(*compactStore[int])
+	if len(s.keys) != 100 {
+		t.Fatalf("deleted IDs should be kept until Realloc, got %d IDs", len(s.keys))
+	}
+
+	g.Realloc()
+	g.AssertRelationships()
+
+	if len(s.keys) != 10 || len(s.dependencies) != 10 || len(s.dependents) != 10 {
+		t.Fatalf("expecting 10 IDs after Realloc, got %d", len(s.keys))
+	}
+
+	if !g.Dependencies(9).Contains(0) || g.EdgeKindOf(9, 8) != KindBuild {
+		t.Fatal("edges should survive Realloc")
+	}
+}
@@ -1,6 +1,7 @@
 package soydepend_test
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -58,6 +59,48 @@ func TestAddDependencies(t *testing.T) {
 	g.AssertRelationships()
 }
 
+func TestCycleError(t *testing.T) {
+	valids := map[string][]string{
+		"b": {"a"},
+		"c": {"b"},
+		"d": {"c"},
+		"x": {"a"},
+	}
+
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, valids)
+
+	err := g.Depend("a", "d")
+	if !errors.Is(err, soydepend.ErrCircularDependency) {
+		t.Fatalf("expecting ErrCircularDependency, got %v", err)
+	}
+
+	var errCycle *soydepend.CycleError[string]
+	if !errors.As(err, &errCycle) {
+		t.Fatalf("expecting CycleError, got %T", err)
+	}
+
+	expected := []string{"d", "c", "b", "a", "d"}
+	if !reflect.DeepEqual(errCycle.Path, expected) {
+		t.Fatalf("unexpected cycle path: expecting %v, got %v", expected, errCycle.Path)
+	}
+
+	err = g.Depend("a", "b")
+	if !errors.As(err, &errCycle) {
+		t.Fatalf("expecting CycleError, got %v", err)
+	}
+
+	expected = []string{"b", "a", "b"}
+	if !reflect.DeepEqual(errCycle.Path, expected) {
+		t.Fatalf("unexpected cycle path: expecting %v, got %v", expected, errCycle.Path)
+	}
+
+	g.AssertRelationships()
+	if g.DependsOn("a", "d") || g.DependsOn("a", "b") {
+		t.Fatal("cyclic edges should not be added")
+	}
+}
+
 func TestDependencies(t *testing.T) {
 	valids := map[string][]string{
 		"b": {"a"},
@@ -116,6 +159,30 @@ func TestDependents(t *testing.T) {
 	assertMapContainsValues(t, deps, []string{"x", "y", "0", "1"})
 }
 
+func TestDirect(t *testing.T) {
+	g := soydepend.New[string]()
+	addValidDependencies(t, g, map[string][]string{
+		"b": {"a"},
+		"c": {"a", "b"},
+	})
+
+	assertSet(t, g.DependenciesDirect("c"), soydepend.NodeSet("a", "b"))
+	assertSet(t, g.DependentsDirect("a"), soydepend.NodeSet("b", "c"))
+
+	// Nodes without direct edges, including unknown nodes, have nil sets
+	for _, node := range []string{"a", "x"} {
+		if deps := g.DependenciesDirect(node); deps != nil {
+			t.Fatalf("expecting nil dependencies for %s, got %v", node, deps)
+		}
+	}
+
+	for _, node := range []string{"c", "x"} {
+		if deps := g.DependentsDirect(node); deps != nil {
+			t.Fatalf("expecting nil dependents for %s, got %v", node, deps)
+		}
+	}
+}
+
 func TestUndepend(t *testing.T) {
 	valids := map[string][]string{
 		"b": {"a"},
@@ -324,6 +391,77 @@ func TestLayersComplex(t *testing.T) {
 	})
 }
 
+func TestIsolatedNodes(t *testing.T) {
+	g := soydepend.New[string]()
+	g.AddNodes("x", "y")
+	g.AddNode("x")
+	g.AssertRelationships()
+
+	if !g.Contains("x") || !g.Contains("y") {
+		t.Fatal("missing isolated nodes")
+	}
+
+	if leaves := g.Leaves(); !reflect.DeepEqual(leaves, soydepend.NodeSet("x", "y")) {
+		t.Fatal("unexpected leaves", leaves)
+	}
+
+	assertLayers(t, &g, []soydepend.Set[string]{
+		soydepend.NodeSet("x", "y"),
+	})
+
+	// Adding existing node preserves its edges
+	if err := g.Depend("b", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AddNodes("a", "b")
+	g.AssertRelationships()
+	if !g.DependsOnDirectly("b", "a") {
+		t.Fatal("AddNode should not drop edges")
+	}
+
+	assertLayers(t, &g, []soydepend.Set[string]{
+		soydepend.NodeSet("a", "x", "y"),
+		soydepend.NodeSet("b"),
+	})
+
+	// b is left isolated after undepend
+	if err := g.Undepend("b", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	assertLayers(t, &g, []soydepend.Set[string]{
+		soydepend.NodeSet("a", "b", "x", "y"),
+	})
+
+	if err := g.Remove("x"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.RemoveForce("y")
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("x", "y"))
+
+	// Autoremove leaves previously isolated nodes alone
+	if err := g.Depend("c", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AddNode("z")
+	g.RemoveAutoRemove("c")
+	g.AssertRelationships()
+	assertNotContains(t, g, soydepend.NodeSet("a", "c"))
+
+	if nodes := g.GraphNodes(); !reflect.DeepEqual(nodes, soydepend.NodeSet("b", "z")) {
+		t.Fatal("unexpected remaining nodes", nodes)
+	}
+
+	g.RemoveAutoRemove("b")
+	g.RemoveAutoRemove("z")
+	assertEmptyGraph(t, g)
+}
+
 func TestRemove(t *testing.T) {
 	g := initTestGraph(t)
 	var err error
@@ -0,0 +1,252 @@
+package soydepend
+
+// Store holds nodes and edges of Graph, along with edge kinds.
+// Other per-node state of Graph, e.g. pins and install reasons, is kept by Graph itself.
+//
+// Graph guarantees that it never adds an edge from a node to itself, or an edge closing a cycle,
+// so a Store does not have to check. SetEdge and RemoveEdge may be called with nodes not in store.
+//
+// Range functions call f with adjacent nodes and edge kinds until f returns false.
+// f must not modify the store.
+//
+// Implementations are not safe for concurrent use, see SyncGraph.
+// Package storetest has a conformance suite that every implementation should pass.
+type Store[T comparable] interface {
+	AddNode(node T)    // Adds node if it is not already in store
+	DeleteNode(node T) // Deletes node and all of its edges
+	HasNode(node T) bool
+	Len() int // Returns the number of nodes
+	RangeNodes(f func(node T) bool)
+
+	SetEdge(dependent, dependency T, kind EdgeKind) // Adds or overwrites edge dependent->dependency, adding missing nodes
+	RemoveEdge(dependent, dependency T)
+	EdgeKind(dependent, dependency T) EdgeKind // Returns kinds of edge dependent->dependency, or 0 if there is no such edge
+	RangeDependencies(node T, f func(dependency T, kind EdgeKind) bool)
+	RangeDependents(node T, f func(dependent T, kind EdgeKind) bool)
+	NumDependencies(node T) int
+	NumDependents(node T) int
+
+	Clone() Store[T]                  // Returns a deep copy of store
+	Compact()                         // Reclaims memory left by deleted nodes and edges
+	Validate() []DanglingReference[T] // Returns invalid references in store
+}
+
+// ClosureStore can be implemented by stores with faster deep traversals
+// than the generic walk in Graph. Graph uses it for Dependencies and Dependents.
+type ClosureStore[T comparable] interface {
+	Store[T]
+	DependencyClosure(node T, mask EdgeKind) Set[T] // Returns deep dependencies of node via edges with kinds in mask
+	DependentClosure(node T, mask EdgeKind) Set[T]  // Returns deep dependents of node via edges with kinds in mask
+}
+
+// NewMapStore returns the default store used by New, which keeps edges
+// in both directions as nested Go maps.
+func NewMapStore[T comparable]() Store[T] {
+	return newMapStore[T]()
+}
+
+// mapStore is the default store, keeping edges in both directions as nested maps
+// from nodes to adjacent nodes to edge kinds
+type mapStore[T comparable] struct {
+	nodes        Set[T]               // All nodes in store
+	dependents   map[T]map[T]EdgeKind // dependency -> dependents -> edge kinds
+	dependencies map[T]map[T]EdgeKind // dependent -> dependencies -> edge kinds
+}
+
+func newMapStore[T comparable]() *mapStore[T] {
+	return &mapStore[T]{
+		nodes:        make(Set[T]),
+		dependents:   make(map[T]map[T]EdgeKind),
+		dependencies: make(map[T]map[T]EdgeKind),
+	}
+}
+
+func (s *mapStore[T]) AddNode(node T)             { s.nodes[node] = struct{}{} }
+func (s *mapStore[T]) HasNode(node T) bool        { return s.nodes.Contains(node) }
+func (s *mapStore[T]) Len() int                   { return len(s.nodes) }
+func (s *mapStore[T]) NumDependencies(node T) int { return len(s.dependencies[node]) }
+func (s *mapStore[T]) NumDependents(node T) int   { return len(s.dependents[node]) }
+
+func (s *mapStore[T]) EdgeKind(dependent, dependency T) EdgeKind {
+	// Note: reading from nil maps will not panic
+	return s.dependencies[dependent][dependency]
+}
+
+func (s *mapStore[T]) DeleteNode(node T) {
+	for dependency := range s.dependencies[node] {
+		removeKind(s.dependents, dependency, node)
+	}
+
+	for dependent := range s.dependents[node] {
+		removeKind(s.dependencies, dependent, node)
+	}
+
+	delete(s.nodes, node)
+	delete(s.dependents, node)
+	delete(s.dependencies, node)
+}
+
+func (s *mapStore[T]) RangeNodes(f func(node T) bool) {
+	for node := range s.nodes {
+		if !f(node) {
+			return
+		}
+	}
+}
+
+func (s *mapStore[T]) SetEdge(dependent, dependency T, kind EdgeKind) {
+	setKind(s.dependents, dependency, dependent, kind)
+	setKind(s.dependencies, dependent, dependency, kind)
+
+	s.nodes[dependency] = struct{}{}
+	s.nodes[dependent] = struct{}{}
+}
+
+func (s *mapStore[T]) RemoveEdge(dependent, dependency T) {
+	removeKind(s.dependents, dependency, dependent)
+	removeKind(s.dependencies, dependent, dependency)
+}
+
+func (s *mapStore[T]) RangeDependencies(node T, f func(dependency T, kind EdgeKind) bool) {
+	for dependency, kind := range s.dependencies[node] {
+		if !f(dependency, kind) {
+			return
+		}
+	}
+}
+
+func (s *mapStore[T]) RangeDependents(node T, f func(dependent T, kind EdgeKind) bool) {
+	for dependent, kind := range s.dependents[node] {
+		if !f(dependent, kind) {
+			return
+		}
+	}
+}
+
+func (s *mapStore[T]) Clone() Store[T] {
+	return &mapStore[T]{
+		nodes:        copyMap(s.nodes),
+		dependents:   copyKinds(s.dependents),
+		dependencies: copyKinds(s.dependencies),
+	}
+}
+
+// Compact allocates new maps and drops the old maps,
+// hopefully to reduce memory footprints from map memory leaks
+// after multiple deletion in large maps.
+func (s *mapStore[T]) Compact() {
+	s.nodes = copyMap(s.nodes)
+	s.dependents = copyKinds(s.dependents)
+	s.dependencies = copyKinds(s.dependencies)
+}
+
+func (s *mapStore[T]) Validate() []DanglingReference[T] {
+	var refs []DanglingReference[T]
+
+	check := func(name string, edges, inverse map[T]map[T]EdgeKind) {
+		for key := range edges {
+			if !s.nodes.Contains(key) {
+				refs = append(refs, DanglingReference[T]{Dangling: DanglingKey, Edges: name, Key: key})
+			}
+
+			for node, kind := range edges[key] {
+				if !s.nodes.Contains(node) {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingNode, Edges: name, Key: key, Node: node})
+				}
+
+				if inverse[node][key] != kind {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingInverse, Edges: name, Key: key, Node: node})
+				}
+
+				if kind == 0 {
+					refs = append(refs, DanglingReference[T]{Dangling: DanglingKind, Edges: name, Key: key, Node: node})
+				}
+			}
+		}
+	}
+
+	check("dependents", s.dependents, s.dependencies)
+	check("dependencies", s.dependencies, s.dependents)
+
+	return refs
+}
+
+func setKind[T comparable](edges map[T]map[T]EdgeKind, key, node T, kind EdgeKind) {
+	kinds := edges[key]
+	if kinds == nil {
+		kinds = make(map[T]EdgeKind)
+		edges[key] = kinds
+	}
+
+	kinds[node] = kind
+}
+
+func removeKind[T comparable](edges map[T]map[T]EdgeKind, key, node T) {
+	kinds := edges[key]
+	if kinds == nil {
+		return
+	}
+
+	delete(kinds, node)
+	if len(kinds) == 0 {
+		delete(edges, key)
+	}
+}
+
+// rangeFunc is either RangeDependencies or RangeDependents of a store
+type rangeFunc[T comparable] func(node T, f func(adjacent T, kind EdgeKind) bool)
+
+// adjacentSet collects nodes adjacent to node into a new set
+func adjacentSet[T comparable](edges rangeFunc[T], node T) Set[T] {
+	set := make(Set[T])
+	edges(node, func(adjacent T, _ EdgeKind) bool {
+		set[adjacent] = struct{}{}
+		return true
+	})
+
+	return set
+}
+
+// directDependents collects direct dependents of node into a new set, or returns nil if there are none
+func (g *Graph[T]) directDependents(node T) Set[T] {
+	return nonEmpty(adjacentSet(g.store.RangeDependents, node))
+}
+
+// directDependencies collects direct dependencies of node into a new set, or returns nil if there are none
+func (g *Graph[T]) directDependencies(node T) Set[T] {
+	return nonEmpty(adjacentSet(g.store.RangeDependencies, node))
+}
+
+func nonEmpty[T comparable](set Set[T]) Set[T] {
+	if len(set) == 0 {
+		return nil
+	}
+
+	return set
+}
+
+// nodeSet collects all nodes of g into a new set
+func (g *Graph[T]) nodeSet() Set[T] {
+	nodes := make(Set[T], g.store.Len())
+	g.store.RangeNodes(func(node T) bool {
+		nodes[node] = struct{}{}
+		return true
+	})
+
+	return nodes
+}
+
+// edgeMap collects edges of every node of g into a new Edges map,
+// omitting nodes without edges in that direction
+func (g *Graph[T]) edgeMap(edges rangeFunc[T]) Edges[T] {
+	copied := make(Edges[T])
+	g.store.RangeNodes(func(node T) bool {
+		if set := adjacentSet(edges, node); len(set) != 0 {
+			copied[node] = set
+		}
+
+		return true
+	})
+
+	return copied
+}
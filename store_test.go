@@ -0,0 +1,66 @@
+package soydepend_test
+
+import (
+	"cmp"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestNewWithStore(t *testing.T) {
+	for _, newStore := range []func() soydepend.Store[string]{soydepend.NewMapStore[string], soydepend.NewCompactStore[string]} {
+		// Edges are added in reverse topological order, bypassing Graph
+		s := newStore()
+		s.SetEdge("d", "c", soydepend.KindRuntime)
+		s.SetEdge("c", "b", soydepend.KindRuntime)
+		s.SetEdge("b", "a", soydepend.KindBuild)
+		s.AddNode("x")
+
+		g := soydepend.NewWithStore(s)
+		g.AssertRelationships()
+
+		expected := []string{"a", "x", "b", "c", "d"}
+		if sorted := g.TopoSort(cmp.Compare[string]); !reflect.DeepEqual(sorted, expected) {
+			t.Fatalf("unexpected order %v, expecting %v", sorted, expected)
+		}
+
+		err := g.Depend("a", "d")
+		if !errors.Is(err, soydepend.ErrCircularDependency) {
+			t.Fatal("expecting ErrCircularDependency, got", err)
+		}
+
+		err = g.Depend("x", "d")
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		g.AssertRelationships()
+	}
+}
+
+func TestNewWithStoreCyclic(t *testing.T) {
+	s := soydepend.NewMapStore[string]()
+	s.SetEdge("a", "b", soydepend.KindRuntime)
+	s.SetEdge("b", "a", soydepend.KindRuntime)
+	s.AddNode("c")
+
+	g := soydepend.NewWithStore(s)
+
+	var errIntegrity *soydepend.IntegrityError[string]
+	if !errors.As(g.Validate(), &errIntegrity) {
+		t.Fatal("expecting IntegrityError from cyclic store")
+	}
+
+	unordered := soydepend.NodeSet[string]()
+	for _, ref := range errIntegrity.References {
+		if ref.Dangling == soydepend.DanglingUnordered {
+			unordered[ref.Key] = struct{}{}
+		}
+	}
+
+	if !reflect.DeepEqual(unordered, soydepend.NodeSet("a", "b")) {
+		t.Fatalf("unexpected unordered nodes %v", unordered)
+	}
+}
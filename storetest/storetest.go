@@ -0,0 +1,363 @@
+// Package storetest implements a conformance suite for soydepend.Store implementations.
+package storetest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+var kinds = []soydepend.EdgeKind{
+	soydepend.KindBuild,
+	soydepend.KindRuntime,
+	soydepend.KindTest,
+	soydepend.KindOptional,
+}
+
+// Run tests a store implementation with subtests.
+// newStore must return a new, empty store on every call.
+//
+// If the store implements soydepend.ClosureStore, its closures are tested too.
+func Run(t *testing.T, newStore func() soydepend.Store[int]) {
+	t.Run("Nodes", func(t *testing.T) { testNodes(t, newStore()) })
+	t.Run("Edges", func(t *testing.T) { testEdges(t, newStore()) })
+	t.Run("DeleteNode", func(t *testing.T) { testDeleteNode(t, newStore()) })
+	t.Run("Range", func(t *testing.T) { testRange(t, newStore()) })
+	t.Run("Clone", func(t *testing.T) { testClone(t, newStore()) })
+	t.Run("Random", func(t *testing.T) { testRandom(t, newStore()) })
+}
+
+func testNodes(t *testing.T, s soydepend.Store[int]) {
+	m := newModel()
+	assertModel(t, s, m)
+
+	for _, node := range []int{1, 2, 3, 2} {
+		s.AddNode(node)
+		m.addNode(node)
+	}
+
+	assertModel(t, s, m)
+
+	s.DeleteNode(2)
+	s.DeleteNode(4) // Not in store
+	m.deleteNode(2)
+
+	assertModel(t, s, m)
+}
+
+func testEdges(t *testing.T, s soydepend.Store[int]) {
+	m := newModel()
+
+	// SetEdge adds missing nodes
+	s.SetEdge(2, 1, soydepend.KindBuild)
+	m.setEdge(2, 1, soydepend.KindBuild)
+	assertModel(t, s, m)
+
+	// SetEdge overwrites kinds
+	s.SetEdge(2, 1, soydepend.KindRuntime|soydepend.KindTest)
+	m.setEdge(2, 1, soydepend.KindRuntime|soydepend.KindTest)
+	s.SetEdge(3, 1, soydepend.KindRuntime)
+	m.setEdge(3, 1, soydepend.KindRuntime)
+	s.SetEdge(3, 2, soydepend.KindOptional)
+	m.setEdge(3, 2, soydepend.KindOptional)
+	assertModel(t, s, m)
+
+	if kind := s.EdgeKind(1, 2); kind != 0 {
+		t.Fatalf("unexpected kind %v of inverse edge 1->2", kind)
+	}
+
+	// RemoveEdge keeps both nodes, and ignores missing edges and nodes
+	s.RemoveEdge(2, 1)
+	m.removeEdge(2, 1)
+	s.RemoveEdge(2, 1)
+	s.RemoveEdge(1, 3)
+	s.RemoveEdge(4, 5)
+	assertModel(t, s, m)
+}
+
+func testDeleteNode(t *testing.T, s soydepend.Store[int]) {
+	m := newModel()
+	for _, edge := range [][2]int{{2, 1}, {3, 2}, {4, 2}, {4, 1}} {
+		s.SetEdge(edge[0], edge[1], soydepend.KindRuntime)
+		m.setEdge(edge[0], edge[1], soydepend.KindRuntime)
+	}
+
+	// 2 has both dependents and dependencies
+	s.DeleteNode(2)
+	m.deleteNode(2)
+	assertModel(t, s, m)
+
+	// Deleted nodes can be added again without their old edges
+	s.AddNode(2)
+	m.addNode(2)
+	s.SetEdge(2, 4, soydepend.KindBuild)
+	m.setEdge(2, 4, soydepend.KindBuild)
+	assertModel(t, s, m)
+}
+
+func testRange(t *testing.T, s soydepend.Store[int]) {
+	for i := 1; i <= 10; i++ {
+		s.SetEdge(0, i, soydepend.KindRuntime)
+		s.SetEdge(i, 11, soydepend.KindRuntime)
+	}
+
+	calls := 0
+	stop := func(int, soydepend.EdgeKind) bool {
+		calls++
+		return calls < 3
+	}
+
+	s.RangeDependencies(0, stop)
+	if calls != 3 {
+		t.Fatalf("RangeDependencies called f %d times after it returned false", calls-3)
+	}
+
+	calls = 0
+	s.RangeDependents(11, stop)
+	if calls != 3 {
+		t.Fatalf("RangeDependents called f %d times after it returned false", calls-3)
+	}
+
+	calls = 0
+	s.RangeNodes(func(int) bool { return stop(0, 0) })
+	if calls != 3 {
+		t.Fatalf("RangeNodes called f %d times after it returned false", calls-3)
+	}
+
+	// Ranging over nodes not in store calls nothing
+	s.RangeDependencies(12, func(int, soydepend.EdgeKind) bool {
+		t.Fatal("unexpected call from RangeDependencies of missing node")
+		return true
+	})
+
+	s.RangeDependents(12, func(int, soydepend.EdgeKind) bool {
+		t.Fatal("unexpected call from RangeDependents of missing node")
+		return true
+	})
+}
+
+func testClone(t *testing.T, s soydepend.Store[int]) {
+	m := newModel()
+	for _, edge := range [][2]int{{2, 1}, {3, 2}, {3, 1}} {
+		s.SetEdge(edge[0], edge[1], soydepend.KindRuntime)
+		m.setEdge(edge[0], edge[1], soydepend.KindRuntime)
+	}
+
+	cloned := s.Clone()
+	assertModel(t, cloned, m)
+
+	// Changes to either store must not leak into the other
+	cloned.DeleteNode(1)
+	cloned.SetEdge(4, 3, soydepend.KindBuild)
+	s.SetEdge(3, 2, soydepend.KindTest)
+	s.AddNode(5)
+	assertModel(t, cloned, &model{
+		nodes: map[int]bool{2: true, 3: true, 4: true},
+		edges: map[[2]int]soydepend.EdgeKind{{3, 2}: soydepend.KindRuntime, {4, 3}: soydepend.KindBuild},
+	})
+
+	m.setEdge(3, 2, soydepend.KindTest)
+	m.addNode(5)
+	assertModel(t, s, m)
+}
+
+// testRandom applies random operations to both s and a reference model,
+// compacting s every now and then. Edges only go from larger to smaller nodes,
+// so closures are well-defined like in Graph.
+func testRandom(t *testing.T, s soydepend.Store[int]) {
+	rng := rand.New(rand.NewSource(1))
+	m := newModel()
+
+	for i := 0; i < 3000; i++ {
+		dependency := rng.Intn(49)
+		dependent := dependency + 1 + rng.Intn(50-dependency)
+
+		switch op := rng.Intn(20); {
+		case op < 2:
+			s.AddNode(dependent)
+			m.addNode(dependent)
+		case op < 11:
+			kind := kinds[rng.Intn(len(kinds))] | s.EdgeKind(dependent, dependency)
+			s.SetEdge(dependent, dependency, kind)
+			m.setEdge(dependent, dependency, kind)
+		case op < 16:
+			s.RemoveEdge(dependent, dependency)
+			m.removeEdge(dependent, dependency)
+		case op < 19:
+			s.DeleteNode(dependent)
+			m.deleteNode(dependent)
+		default:
+			s.Compact()
+		}
+
+		if i%100 == 0 {
+			assertModel(t, s, m)
+		}
+	}
+
+	assertModel(t, s, m)
+	s.Compact()
+	assertModel(t, s, m)
+}
+
+// model is the reference store
+type model struct {
+	nodes map[int]bool
+	edges map[[2]int]soydepend.EdgeKind // dependent, dependency -> kinds
+}
+
+func newModel() *model {
+	return &model{nodes: make(map[int]bool), edges: make(map[[2]int]soydepend.EdgeKind)}
+}
+
+func (m *model) addNode(node int) { m.nodes[node] = true }
+
+func (m *model) setEdge(dependent, dependency int, kind soydepend.EdgeKind) {
+	m.nodes[dependent], m.nodes[dependency] = true, true
+	m.edges[[2]int{dependent, dependency}] = kind
+}
+
+func (m *model) removeEdge(dependent, dependency int) {
+	delete(m.edges, [2]int{dependent, dependency})
+}
+
+func (m *model) deleteNode(node int) {
+	delete(m.nodes, node)
+	for edge := range m.edges {
+		if edge[0] == node || edge[1] == node {
+			delete(m.edges, edge)
+		}
+	}
+}
+
+// adjacent returns nodes adjacent to node, with from and to being
+// indices of node and adjacent nodes in edges
+func (m *model) adjacent(node, from, to int) map[int]soydepend.EdgeKind {
+	adjacent := make(map[int]soydepend.EdgeKind)
+	for edge, kind := range m.edges {
+		if edge[from] == node {
+			adjacent[edge[to]] = kind
+		}
+	}
+
+	return adjacent
+}
+
+func (m *model) closure(node, from, to int, mask soydepend.EdgeKind) soydepend.Set[int] {
+	closure := make(soydepend.Set[int])
+	queue := []int{node}
+
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for adjacent, kind := range m.adjacent(current, from, to) {
+			if kind&mask != 0 && !closure.Contains(adjacent) {
+				closure[adjacent] = struct{}{}
+				queue = append(queue, adjacent)
+			}
+		}
+	}
+
+	return closure
+}
+
+func collect(rangeFunc func(int, func(int, soydepend.EdgeKind) bool), node int) map[int]soydepend.EdgeKind {
+	adjacent := make(map[int]soydepend.EdgeKind)
+	rangeFunc(node, func(node int, kind soydepend.EdgeKind) bool {
+		if _, ok := adjacent[node]; ok {
+			panic("node visited twice")
+		}
+
+		adjacent[node] = kind
+		return true
+	})
+
+	return adjacent
+}
+
+func assertModel(t *testing.T, s soydepend.Store[int], m *model) {
+	t.Helper()
+
+	if s.Len() != len(m.nodes) {
+		t.Fatalf("unexpected Len %d, expecting %d", s.Len(), len(m.nodes))
+	}
+
+	nodes := make(map[int]bool)
+	s.RangeNodes(func(node int) bool {
+		nodes[node] = true
+		return true
+	})
+
+	if !reflect.DeepEqual(nodes, m.nodes) {
+		t.Fatalf("unexpected nodes %v, expecting %v", nodes, m.nodes)
+	}
+
+	for node := -1; node <= 51; node++ {
+		if s.HasNode(node) != m.nodes[node] {
+			t.Fatalf("unexpected HasNode(%d) %v", node, s.HasNode(node))
+		}
+
+		dependencies, dependents := m.adjacent(node, 0, 1), m.adjacent(node, 1, 0)
+
+		if actual := collect(s.RangeDependencies, node); !reflect.DeepEqual(actual, dependencies) {
+			t.Fatalf("unexpected dependencies of %d: %v, expecting %v", node, actual, dependencies)
+		}
+
+		if actual := collect(s.RangeDependents, node); !reflect.DeepEqual(actual, dependents) {
+			t.Fatalf("unexpected dependents of %d: %v, expecting %v", node, actual, dependents)
+		}
+
+		if s.NumDependencies(node) != len(dependencies) {
+			t.Fatalf("unexpected NumDependencies(%d) %d, expecting %d", node, s.NumDependencies(node), len(dependencies))
+		}
+
+		if s.NumDependents(node) != len(dependents) {
+			t.Fatalf("unexpected NumDependents(%d) %d, expecting %d", node, s.NumDependents(node), len(dependents))
+		}
+
+		for dependency, kind := range dependencies {
+			if s.EdgeKind(node, dependency) != kind {
+				t.Fatalf("unexpected kind of %d->%d: %v, expecting %v", node, dependency, s.EdgeKind(node, dependency), kind)
+			}
+		}
+	}
+
+	if refs := s.Validate(); len(refs) != 0 {
+		t.Fatalf("unexpected dangling references %v", refs)
+	}
+
+	closure, ok := s.(soydepend.ClosureStore[int])
+	if !ok {
+		return
+	}
+
+	for node := range m.nodes {
+		for _, mask := range []soydepend.EdgeKind{soydepend.KindAll, soydepend.KindBuild | soydepend.KindTest} {
+			if actual, expected := closure.DependencyClosure(node, mask), m.closure(node, 0, 1, mask); !sameSet(actual, expected) {
+				t.Fatalf("unexpected dependency closure of %d with mask %v: %v, expecting %v", node, mask, actual, expected)
+			}
+
+			if actual, expected := closure.DependentClosure(node, mask), m.closure(node, 1, 0, mask); !sameSet(actual, expected) {
+				t.Fatalf("unexpected dependent closure of %d with mask %v: %v, expecting %v", node, mask, actual, expected)
+			}
+		}
+	}
+}
+
+// sameSet compares sets, treating nil and empty sets as equal
+func sameSet(a, b soydepend.Set[int]) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for node := range a {
+		if !b.Contains(node) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,16 @@
+package storetest_test
+
+import (
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+	"github.com/soyart/soydepend-go/storetest"
+)
+
+func TestMapStore(t *testing.T) {
+	storetest.Run(t, soydepend.NewMapStore[int])
+}
+
+func TestCompactStore(t *testing.T) {
+	storetest.Run(t, soydepend.NewCompactStore[int])
+}
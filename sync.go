@@ -0,0 +1,471 @@
+package soydepend
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncGraph wraps Graph with a reader/writer lock,
+// so that it can be safely shared between goroutines.
+//
+// Read-only methods hold the read lock, while methods that modify
+// the graph hold the write lock. Use Update or View for multi-step critical sections.
+type SyncGraph[T comparable] struct {
+	mut   sync.RWMutex
+	graph Graph[T]
+}
+
+func NewSync[T comparable]() *SyncGraph[T] {
+	return &SyncGraph[T]{graph: New[T]()}
+}
+
+// Update calls f with the underlying graph while holding the write lock.
+// The graph must not be retained after f returns.
+func (s *SyncGraph[T]) Update(f func(g *Graph[T]) error) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return f(&s.graph)
+}
+
+// View calls f with the underlying graph while holding the read lock.
+// f must not modify the graph, and the graph must not be retained after f returns.
+func (s *SyncGraph[T]) View(f func(g *Graph[T]) error) error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return f(&s.graph)
+}
+
+func (s *SyncGraph[T]) Contains(node T) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Contains(node)
+}
+
+func (s *SyncGraph[T]) GraphNodes() Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.GraphNodes()
+}
+
+func (s *SyncGraph[T]) GraphDependents() Edges[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.GraphDependents()
+}
+
+func (s *SyncGraph[T]) GraphDependencies() Edges[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.GraphDependencies()
+}
+
+func (s *SyncGraph[T]) DependentsDirect(node T) Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependentsDirect(node)
+}
+
+func (s *SyncGraph[T]) DependenciesDirect(node T) Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependenciesDirect(node)
+}
+
+// Clone returns an unsynchronized copy of the underlying graph
+func (s *SyncGraph[T]) Clone() Graph[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Clone()
+}
+
+func (s *SyncGraph[T]) AddNode(node T) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.AddNode(node)
+}
+
+func (s *SyncGraph[T]) AddNodes(nodes ...T) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.AddNodes(nodes...)
+}
+
+func (s *SyncGraph[T]) Depend(dependent, dependency T, kinds ...EdgeKind) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Depend(dependent, dependency, kinds...)
+}
+
+func (s *SyncGraph[T]) Undepend(dependent, dependency T, kinds ...EdgeKind) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Undepend(dependent, dependency, kinds...)
+}
+
+func (s *SyncGraph[T]) DependsOn(dependent, dependency T) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependsOn(dependent, dependency)
+}
+
+func (s *SyncGraph[T]) EdgeKindOf(dependent, dependency T) EdgeKind {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.EdgeKindOf(dependent, dependency)
+}
+
+func (s *SyncGraph[T]) DependsOnDirectly(dependent, dependency T) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependsOnDirectly(dependent, dependency)
+}
+
+func (s *SyncGraph[T]) Leaves() Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Leaves()
+}
+
+func (s *SyncGraph[T]) Dependencies(node T, kinds ...EdgeKind) Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Dependencies(node, kinds...)
+}
+
+func (s *SyncGraph[T]) Dependents(node T, kinds ...EdgeKind) Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Dependents(node, kinds...)
+}
+
+func (s *SyncGraph[T]) Layers(kinds ...EdgeKind) []Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Layers(kinds...)
+}
+
+func (s *SyncGraph[T]) CriticalPath(
+	duration func(node T) float64,
+	weight func(dependent, dependency T) float64,
+) CriticalPath[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.CriticalPath(duration, weight)
+}
+
+func (s *SyncGraph[T]) RemoveAutoRemove(target T, kinds ...EdgeKind) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.RemoveAutoRemove(target, kinds...)
+}
+
+func (s *SyncGraph[T]) TryRemoveAutoRemove(target T, kinds ...EdgeKind) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.TryRemoveAutoRemove(target, kinds...)
+}
+
+func (s *SyncGraph[T]) RemoveForce(target T, kinds ...EdgeKind) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.RemoveForce(target, kinds...)
+}
+
+func (s *SyncGraph[T]) TryRemoveForce(target T, kinds ...EdgeKind) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.TryRemoveForce(target, kinds...)
+}
+
+func (s *SyncGraph[T]) Remove(target T, kinds ...EdgeKind) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Remove(target, kinds...)
+}
+
+func (s *SyncGraph[T]) PlanRemove(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.PlanRemove(target, kinds...)
+}
+
+func (s *SyncGraph[T]) PlanRemoveForce(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.PlanRemoveForce(target, kinds...)
+}
+
+func (s *SyncGraph[T]) PlanRemoveAutoRemove(target T, kinds ...EdgeKind) (*RemovalPlan[T], error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.PlanRemoveAutoRemove(target, kinds...)
+}
+
+func (s *SyncGraph[T]) ApplyPlan(plan *RemovalPlan[T]) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.ApplyPlan(plan)
+}
+
+func (s *SyncGraph[T]) SetInstallReason(node T, reason InstallReason) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.SetInstallReason(node, reason)
+}
+
+func (s *SyncGraph[T]) InstallReasonOf(node T) InstallReason {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.InstallReasonOf(node)
+}
+
+func (s *SyncGraph[T]) Orphans() Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Orphans()
+}
+
+func (s *SyncGraph[T]) RemoveOrphans() Set[T] {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.RemoveOrphans()
+}
+
+func (s *SyncGraph[T]) Pin(node T) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Pin(node)
+}
+
+func (s *SyncGraph[T]) Unpin(node T) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.Unpin(node)
+}
+
+func (s *SyncGraph[T]) IsPinned(node T) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.IsPinned(node)
+}
+
+func (s *SyncGraph[T]) Pinned() Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Pinned()
+}
+
+func (s *SyncGraph[T]) SetPinPolicy(policy PinPolicy) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.SetPinPolicy(policy)
+}
+
+func (s *SyncGraph[T]) PinPolicy() PinPolicy {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.PinPolicy()
+}
+
+func (s *SyncGraph[T]) MarkDirty(node T) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.MarkDirty(node)
+}
+
+func (s *SyncGraph[T]) MarkClean(node T) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.MarkClean(node)
+}
+
+func (s *SyncGraph[T]) IsDirty(node T) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.IsDirty(node)
+}
+
+func (s *SyncGraph[T]) Dirty() Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Dirty()
+}
+
+func (s *SyncGraph[T]) DirtySet(kinds ...EdgeKind) Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DirtySet(kinds...)
+}
+
+func (s *SyncGraph[T]) RebuildOrder(kinds ...EdgeKind) []Set[T] {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.RebuildOrder(kinds...)
+}
+
+// Rebuild calls Graph.Rebuild with the write lock held, so build must not call methods of s
+func (s *SyncGraph[T]) Rebuild(build func(node T) (changed bool, err error), kinds ...EdgeKind) (Set[T], error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Rebuild(build, kinds...)
+}
+
+func (s *SyncGraph[T]) SetDigest(node T, digest []byte) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.SetDigest(node, digest)
+}
+
+func (s *SyncGraph[T]) Digest(node T) ([]byte, bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Digest(node)
+}
+
+// Hash holds the write lock, since computed hashes are cached in the graph
+func (s *SyncGraph[T]) Hash(node T) (Hash, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.Hash(node)
+}
+
+func (s *SyncGraph[T]) LeavesFunc(compare func(a, b T) int) []T {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.LeavesFunc(compare)
+}
+
+func (s *SyncGraph[T]) DependenciesFunc(node T, compare func(a, b T) int, kinds ...EdgeKind) []T {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependenciesFunc(node, compare, kinds...)
+}
+
+func (s *SyncGraph[T]) DependentsFunc(node T, compare func(a, b T) int, kinds ...EdgeKind) []T {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.DependentsFunc(node, compare, kinds...)
+}
+
+func (s *SyncGraph[T]) LayersFunc(compare func(a, b T) int, kinds ...EdgeKind) [][]T {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.LayersFunc(compare, kinds...)
+}
+
+func (s *SyncGraph[T]) TopoSort(compare func(a, b T) int, kinds ...EdgeKind) []T {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.TopoSort(compare, kinds...)
+}
+
+func (s *SyncGraph[T]) WriteDOT(w io.Writer, opts DOTOptions[T]) error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.WriteDOT(w, opts)
+}
+
+func (s *SyncGraph[T]) WriteMermaid(w io.Writer, opts MermaidOptions[T]) error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.WriteMermaid(w, opts)
+}
+
+func (s *SyncGraph[T]) MarshalJSON() ([]byte, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.MarshalJSON()
+}
+
+func (s *SyncGraph[T]) UnmarshalJSON(data []byte) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.graph.UnmarshalJSON(data)
+}
+
+func (s *SyncGraph[T]) Delete(target T) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.Delete(target)
+}
+
+func (s *SyncGraph[T]) Realloc() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.graph.Realloc()
+}
+
+func (s *SyncGraph[T]) Validate() error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.graph.Validate()
+}
+
+func (s *SyncGraph[T]) AssertRelationships() {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	s.graph.AssertRelationships()
+}
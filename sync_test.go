@@ -0,0 +1,122 @@
+package soydepend_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestSyncGraphConcurrent(t *testing.T) {
+	const (
+		writers = 4
+		readers = 8
+		edges   = 100
+	)
+
+	g := soydepend.NewSync[string]()
+	var wg sync.WaitGroup
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			// Each writer builds its own chain w/0 <- w/1 <- ... <- w/edges
+			for i := 1; i <= edges; i++ {
+				dependent := fmt.Sprintf("%d/%d", w, i)
+				dependency := fmt.Sprintf("%d/%d", w, i-1)
+
+				err := g.Depend(dependent, dependency)
+				if err != nil {
+					t.Error("unexpected error:", err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+
+			node := fmt.Sprintf("%d/%d", r%writers, edges/2)
+			for i := 0; i < edges; i++ {
+				_ = g.Dependencies(node)
+				_ = g.Dependents(node)
+				_ = g.Layers()
+				_ = g.DependenciesDirect(node)
+			}
+		}(r)
+	}
+
+	wg.Wait()
+	g.AssertRelationships()
+
+	for w := 0; w < writers; w++ {
+		deps := g.Dependencies(fmt.Sprintf("%d/%d", w, edges))
+		if len(deps) != edges {
+			t.Fatalf("expecting %d dependencies for writer %d, got %d", edges, w, len(deps))
+		}
+	}
+
+	if layers := g.Layers(); len(layers) != edges+1 {
+		t.Fatalf("expecting %d layers, got %d", edges+1, len(layers))
+	}
+}
+
+func TestSyncGraphUpdate(t *testing.T) {
+	g := soydepend.NewSync[string]()
+	errAbort := errors.New("abort")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := g.Update(func(g *soydepend.Graph[string]) error {
+				node := fmt.Sprint(i)
+				if err := g.Depend(node, "root"); err != nil {
+					return err
+				}
+
+				// No other goroutines can observe node without its edge
+				if !g.DependsOnDirectly(node, "root") {
+					return errAbort
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+		}(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := g.View(func(g *soydepend.Graph[string]) error {
+				for node := range g.GraphNodes() {
+					if node != "root" && !g.DependsOnDirectly(node, "root") {
+						return fmt.Errorf("node %s without edge to root", node)
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if n := len(g.Dependents("root")); n != 16 {
+		t.Fatalf("expecting 16 dependents, got %d", n)
+	}
+}
@@ -0,0 +1,105 @@
+package soydepend
+
+import "slices"
+
+// Graph maintains a topological order of its nodes incrementally with
+// the Pearce–Kelly algorithm: every dependency is ordered before its dependents.
+// When Depend adds an edge that agrees with the order, e.g. to a new node, no search is needed.
+// Otherwise only nodes ordered between the two endpoints are searched and reordered,
+// instead of the whole dependency closure.
+
+// addOrder assigns node the last position in topological order, if it has none
+func (g *Graph[T]) addOrder(node T) {
+	if _, ok := g.order[node]; ok {
+		return
+	}
+
+	g.order[node] = *g.nextOrder
+	*g.nextOrder++
+}
+
+// addOrderHead assigns node the first position in topological order, if it has none
+func (g *Graph[T]) addOrderHead(node T) {
+	if _, ok := g.order[node]; ok {
+		return
+	}
+
+	*g.headOrder--
+	g.order[node] = *g.headOrder
+}
+
+// reorder restores topological order for a new edge dependent->dependency.
+// It returns false, leaving the order untouched, if the edge would create a cycle.
+func (g *Graph[T]) reorder(dependent, dependency T) bool {
+	lower, upper := g.order[dependent], g.order[dependency]
+	if upper < lower {
+		return true
+	}
+
+	// Deep dependents of dependent ordered before dependency must move after it.
+	// Reaching dependency means it already depends on dependent.
+	forward, ok := g.visitOrder(g.store.RangeDependents, dependent, func(order int) bool { return order <= upper }, equal(dependency))
+	if !ok {
+		return false
+	}
+
+	// Deep dependencies of dependency ordered after dependent must move before it
+	backward, _ := g.visitOrder(g.store.RangeDependencies, dependency, func(order int) bool { return order >= lower }, nil)
+
+	slices.SortFunc(forward, g.byOrder)
+	slices.SortFunc(backward, g.byOrder)
+
+	moved := append(backward, forward...)
+	orders := make([]int, len(moved))
+	for i := range moved {
+		orders[i] = g.order[moved[i]]
+	}
+
+	slices.Sort(orders)
+	for i := range moved {
+		g.order[moved[i]] = orders[i]
+	}
+
+	return true
+}
+
+// visitOrder walks edges from start, only through nodes whose order is inRange.
+// It returns the visited nodes, or false if a node matching stop is reached.
+func (g *Graph[T]) visitOrder(edges rangeFunc[T], start T, inRange func(order int) bool, stop func(T) bool) ([]T, bool) {
+	visited := Set[T]{start: struct{}{}}
+	stack := []T{start}
+
+	var nodes []T
+	stopped := false
+
+	for len(stack) != 0 && !stopped {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		nodes = append(nodes, current)
+
+		edges(current, func(next T, _ EdgeKind) bool {
+			if stop != nil && stop(next) {
+				stopped = true
+				return false
+			}
+
+			if !visited.Contains(next) && inRange(g.order[next]) {
+				visited[next] = struct{}{}
+				stack = append(stack, next)
+			}
+
+			return true
+		})
+	}
+
+	if stopped {
+		return nil, false
+	}
+
+	return nodes, true
+}
+
+// byOrder compares nodes by their positions in topological order
+func (g *Graph[T]) byOrder(a, b T) int {
+	return g.order[a] - g.order[b]
+}
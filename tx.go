@@ -0,0 +1,162 @@
+package soydepend
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tx stages graph operations, and applies them all at once with Commit.
+// Staged operations do not touch the graph until Commit.
+type Tx[T comparable] struct {
+	graph *Graph[T]
+	ops   []txOp[T]
+}
+
+type txOp[T comparable] struct {
+	name  string
+	apply func(g *Graph[T]) error
+}
+
+// TxError is a staged operation rejected during Commit.
+type TxError struct {
+	Index int    // Index of the operation in the transaction
+	Op    string // Description of the operation, e.g. "depend(b, a)"
+	Err   error
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("tx op %d %s: %s", e.Index, e.Op, e.Err.Error())
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// Begin starts a new transaction on g.
+func (g *Graph[T]) Begin() *Tx[T] {
+	return &Tx[T]{graph: g}
+}
+
+// AddNode stages AddNode.
+func (tx *Tx[T]) AddNode(node T) *Tx[T] {
+	return tx.stage(fmt.Sprintf("add-node(%v)", node), func(g *Graph[T]) error {
+		g.AddNode(node)
+		return nil
+	})
+}
+
+// SetInstallReason stages SetInstallReason.
+func (tx *Tx[T]) SetInstallReason(node T, reason InstallReason) *Tx[T] {
+	return tx.stage(fmt.Sprintf("set-install-reason(%v, %s)", node, reason.String()), func(g *Graph[T]) error {
+		return g.SetInstallReason(node, reason)
+	})
+}
+
+// Pin stages Pin.
+func (tx *Tx[T]) Pin(node T) *Tx[T] {
+	return tx.stage(fmt.Sprintf("pin(%v)", node), func(g *Graph[T]) error {
+		return g.Pin(node)
+	})
+}
+
+// Unpin stages Unpin.
+func (tx *Tx[T]) Unpin(node T) *Tx[T] {
+	return tx.stage(fmt.Sprintf("unpin(%v)", node), func(g *Graph[T]) error {
+		g.Unpin(node)
+		return nil
+	})
+}
+
+// Depend stages Depend.
+func (tx *Tx[T]) Depend(dependent, dependency T, kinds ...EdgeKind) *Tx[T] {
+	return tx.stage(fmt.Sprintf("depend(%v, %v)", dependent, dependency), func(g *Graph[T]) error {
+		return g.Depend(dependent, dependency, kinds...)
+	})
+}
+
+// Undepend stages Undepend.
+func (tx *Tx[T]) Undepend(dependent, dependency T, kinds ...EdgeKind) *Tx[T] {
+	return tx.stage(fmt.Sprintf("undepend(%v, %v)", dependent, dependency), func(g *Graph[T]) error {
+		return g.Undepend(dependent, dependency, kinds...)
+	})
+}
+
+// Remove stages Remove.
+func (tx *Tx[T]) Remove(target T, kinds ...EdgeKind) *Tx[T] {
+	return tx.stage(fmt.Sprintf("remove(%v)", target), func(g *Graph[T]) error {
+		return g.Remove(target, kinds...)
+	})
+}
+
+// RemoveForce stages TryRemoveForce, so that pinned and corrupted graphs reject the operation instead of panicking.
+func (tx *Tx[T]) RemoveForce(target T, kinds ...EdgeKind) *Tx[T] {
+	return tx.stage(fmt.Sprintf("remove-force(%v)", target), func(g *Graph[T]) error {
+		return g.TryRemoveForce(target, kinds...)
+	})
+}
+
+// RemoveAutoRemove stages TryRemoveAutoRemove, so that pinned and corrupted graphs reject the operation instead of panicking.
+func (tx *Tx[T]) RemoveAutoRemove(target T, kinds ...EdgeKind) *Tx[T] {
+	return tx.stage(fmt.Sprintf("remove-autoremove(%v)", target), func(g *Graph[T]) error {
+		return g.TryRemoveAutoRemove(target, kinds...)
+	})
+}
+
+// Delete stages Delete.
+func (tx *Tx[T]) Delete(target T) *Tx[T] {
+	return tx.stage(fmt.Sprintf("delete(%v)", target), func(g *Graph[T]) error {
+		g.Delete(target)
+		return nil
+	})
+}
+
+// Len returns the number of staged operations.
+func (tx *Tx[T]) Len() int {
+	return len(tx.ops)
+}
+
+// Rollback discards all staged operations.
+func (tx *Tx[T]) Rollback() {
+	tx.ops = nil
+}
+
+// Commit applies all staged operations together. They are first applied to a clone
+// of the graph, and if any operation is rejected, the graph is left untouched, and Commit
+// returns a joined error of *TxError listing every rejected operation.
+//
+// Otherwise the operations are applied again to the graph itself, so that copies
+// of the graph keep sharing its state, and the transaction is emptied.
+// The delete hook of the graph, e.g. of ValueGraph, is called with every node
+// deleted by the operations, in the order they are deleted.
+func (tx *Tx[T]) Commit() error {
+	staged := tx.graph.Clone()
+
+	var errs []error
+	for i := range tx.ops {
+		err := tx.ops[i].apply(&staged)
+		if err != nil {
+			errs = append(errs, &TxError{Index: i, Op: tx.ops[i].name, Err: err})
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	// Operations succeeded on an identical clone, so they cannot fail here
+	for i := range tx.ops {
+		err := tx.ops[i].apply(tx.graph)
+		if err != nil {
+			return &TxError{Index: i, Op: tx.ops[i].name, Err: err}
+		}
+	}
+
+	tx.ops = nil
+
+	return nil
+}
+
+func (tx *Tx[T]) stage(name string, apply func(g *Graph[T]) error) *Tx[T] {
+	tx.ops = append(tx.ops, txOp[T]{name: name, apply: apply})
+	return tx
+}
@@ -0,0 +1,110 @@
+package soydepend_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+func TestTxCommit(t *testing.T) {
+	g := initTestGraph(t)
+	h := g // Copies of Graph share state
+
+	err := g.Begin().
+		Depend("x", "d").
+		Depend("z", "y").
+		Undepend("1", "0").
+		RemoveForce("b").
+		Commit()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	if !g.DependsOn("z", "d") {
+		t.Fatal("z should depend on d via y, x")
+	}
+
+	if g.DependsOn("1", "0") {
+		t.Fatal("1 should no longer depend on 0")
+	}
+
+	assertNotContains(t, g, soydepend.NodeSet("b"))
+
+	// Committed changes are seen by copies too
+	h.AssertRelationships()
+	if !h.DependsOn("z", "d") || h.Contains("b") {
+		t.Fatal("copy of graph should see committed changes")
+	}
+}
+
+func TestTxRollbackOnError(t *testing.T) {
+	g := initTestGraph(t)
+	before := g.Clone()
+
+	tx := g.Begin().
+		Depend("x", "d").
+		Depend("a", "y"). // ok in isolation, but cycle a -> y -> x -> d -> c -> a
+		Undepend("d", "a").
+		Depend("e", "e").
+		Remove("c")
+
+	err := tx.Commit()
+	if err == nil {
+		t.Fatal("expecting error from commit")
+	}
+
+	if !errors.Is(err, soydepend.ErrCircularDependency) {
+		t.Fatal("expecting ErrCircularDependency, got", err)
+	}
+
+	if !errors.Is(err, soydepend.ErrNoSuchDependency) {
+		t.Fatal("expecting ErrNoSuchDependency, got", err)
+	}
+
+	if !errors.Is(err, soydepend.ErrDependsOnSelf) {
+		t.Fatal("expecting ErrDependsOnSelf, got", err)
+	}
+
+	if !errors.Is(err, soydepend.ErrDependentExists) {
+		t.Fatal("expecting ErrDependentExists, got", err)
+	}
+
+	var rejected []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var errTx *soydepend.TxError
+		if !errors.As(e, &errTx) {
+			t.Fatalf("unexpected error type %T", e)
+		}
+
+		rejected = append(rejected, errTx.Index)
+	}
+
+	if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(rejected, expected) {
+		t.Fatalf("unexpected rejected ops: expecting %v, got %v", expected, rejected)
+	}
+
+	g.AssertRelationships()
+	if !reflect.DeepEqual(g.GraphNodes(), before.GraphNodes()) {
+		t.Fatal("nodes changed after failed commit")
+	}
+
+	if !reflect.DeepEqual(g.GraphDependencies(), before.GraphDependencies()) {
+		t.Fatal("dependencies changed after failed commit")
+	}
+
+	if !reflect.DeepEqual(g.GraphDependents(), before.GraphDependents()) {
+		t.Fatal("dependents changed after failed commit")
+	}
+
+	if tx.Len() != 5 {
+		t.Fatal("failed commit should keep staged ops")
+	}
+
+	tx.Rollback()
+	if tx.Len() != 0 {
+		t.Fatal("rollback should discard staged ops")
+	}
+}
@@ -0,0 +1,165 @@
+package soydepend
+
+import "errors"
+
+// ValueGraph is a Graph whose nodes carry values of type V, keyed by node K.
+//
+// Values are dropped whenever their nodes are deleted from the graph,
+// including via promoted Graph methods and transactions. Removal methods
+// of ValueGraph also return the values of removed nodes.
+type ValueGraph[K comparable, V any] struct {
+	Graph[K]
+	state *valueState[K, V]
+}
+
+type valueState[K comparable, V any] struct {
+	values  map[K]V
+	removed map[K]V // Collects values dropped during a removal, nil if not collecting
+}
+
+func NewValueGraph[K comparable, V any]() ValueGraph[K, V] {
+	return newValueGraph(New[K](), make(map[K]V))
+}
+
+func newValueGraph[K comparable, V any](g Graph[K], values map[K]V) ValueGraph[K, V] {
+	state := &valueState[K, V]{values: values}
+	g.onDelete = state.drop
+
+	return ValueGraph[K, V]{Graph: g, state: state}
+}
+
+// Put sets the value of key, adding key to graph with AddNode if it is not already in graph.
+// Like AddNode, Put leaves install reasons alone, so new keys are InstallDependency.
+func (g *ValueGraph[K, V]) Put(key K, value V) {
+	g.AddNode(key)
+	g.state.values[key] = value
+}
+
+// Value returns the value of key, and whether key has a value
+func (g *ValueGraph[K, V]) Value(key K) (V, bool) {
+	value, ok := g.state.values[key]
+	return value, ok
+}
+
+func (g *ValueGraph[K, V]) Values() map[K]V { return copyMap(g.state.values) } // Returns a copy of all values
+
+func (g *ValueGraph[K, V]) Clone() ValueGraph[K, V] {
+	return newValueGraph(g.Graph.Clone(), copyMap(g.state.values))
+}
+
+func (g *ValueGraph[K, V]) Realloc() {
+	g.Graph.Realloc()
+	g.state.values = copyMap(g.state.values)
+}
+
+// Delete deletes target like Graph.Delete, and returns its value
+func (g *ValueGraph[K, V]) Delete(target K) (V, bool) {
+	value, ok := g.state.values[target]
+	g.Graph.Delete(target)
+
+	return value, ok
+}
+
+// Remove removes target like Graph.Remove, and returns values of removed nodes
+func (g *ValueGraph[K, V]) Remove(target K, kinds ...EdgeKind) (map[K]V, error) {
+	return g.collect(func() error { return g.Graph.Remove(target, kinds...) })
+}
+
+// RemoveForce removes target like Graph.RemoveForce, and returns values of removed nodes
+func (g *ValueGraph[K, V]) RemoveForce(target K, kinds ...EdgeKind) map[K]V {
+	removed, _ := g.collect(func() error {
+		g.Graph.RemoveForce(target, kinds...)
+		return nil
+	})
+
+	return removed
+}
+
+// TryRemoveForce removes target like Graph.TryRemoveForce, and returns values of removed nodes
+func (g *ValueGraph[K, V]) TryRemoveForce(target K, kinds ...EdgeKind) (map[K]V, error) {
+	return g.collect(func() error { return g.Graph.TryRemoveForce(target, kinds...) })
+}
+
+// RemoveAutoRemove removes target like Graph.RemoveAutoRemove, and returns values of removed nodes
+func (g *ValueGraph[K, V]) RemoveAutoRemove(target K, kinds ...EdgeKind) map[K]V {
+	removed, _ := g.collect(func() error {
+		g.Graph.RemoveAutoRemove(target, kinds...)
+		return nil
+	})
+
+	return removed
+}
+
+// TryRemoveAutoRemove removes target like Graph.TryRemoveAutoRemove, and returns values of removed nodes
+func (g *ValueGraph[K, V]) TryRemoveAutoRemove(target K, kinds ...EdgeKind) (map[K]V, error) {
+	return g.collect(func() error { return g.Graph.TryRemoveAutoRemove(target, kinds...) })
+}
+
+// RemoveOrphans removes orphans like Graph.RemoveOrphans, and returns values of removed nodes
+func (g *ValueGraph[K, V]) RemoveOrphans() map[K]V {
+	removed, _ := g.collect(func() error {
+		g.Graph.RemoveOrphans()
+		return nil
+	})
+
+	return removed
+}
+
+// ApplyPlan executes plan like Graph.ApplyPlan, and returns values of removed nodes
+func (g *ValueGraph[K, V]) ApplyPlan(plan *RemovalPlan[K]) (map[K]V, error) {
+	return g.collect(func() error { return g.Graph.ApplyPlan(plan) })
+}
+
+// Validate validates g like Graph.Validate, and also reports values of keys not in graph
+func (g *ValueGraph[K, V]) Validate() error {
+	err := g.Graph.Validate()
+
+	var refs []DanglingReference[K]
+	var errIntegrity *IntegrityError[K]
+	if errors.As(err, &errIntegrity) {
+		refs = errIntegrity.References
+	}
+
+	for key := range g.state.values {
+		if !g.store.HasNode(key) {
+			refs = append(refs, DanglingReference[K]{Dangling: DanglingKey, Edges: "values", Key: key})
+		}
+	}
+
+	if len(refs) != 0 {
+		return &IntegrityError[K]{References: refs}
+	}
+
+	return nil
+}
+
+// AssertRelationships panics with the error from Validate if invalid references are found
+func (g *ValueGraph[K, V]) AssertRelationships() {
+	err := g.Validate()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// collect calls f and returns values of nodes deleted by f, even if f fails
+func (g *ValueGraph[K, V]) collect(f func() error) (map[K]V, error) {
+	g.state.removed = make(map[K]V)
+	defer func() { g.state.removed = nil }()
+
+	err := f()
+
+	return g.state.removed, err
+}
+
+func (s *valueState[K, V]) drop(key K) {
+	value, ok := s.values[key]
+	if !ok {
+		return
+	}
+
+	if s.removed != nil {
+		s.removed[key] = value
+	}
+
+	delete(s.values, key)
+}
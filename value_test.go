@@ -0,0 +1,152 @@
+package soydepend_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyart/soydepend-go"
+)
+
+type testPackage struct {
+	name    string
+	version int
+}
+
+func initTestValueGraph(t *testing.T) soydepend.ValueGraph[string, testPackage] {
+	g := soydepend.NewValueGraph[string, testPackage]()
+	for _, name := range []string{"a", "b", "c", "d", "x", "y"} {
+		g.Put(name, testPackage{name: name, version: 1})
+	}
+
+	addValidDependencies(t, g.Graph, map[string][]string{
+		"b": {"a"},
+		"c": {"a"},
+		"d": {"c"},
+		"y": {"x"},
+	})
+
+	g.AssertRelationships()
+
+	return g
+}
+
+func TestValueGraph(t *testing.T) {
+	g := initTestValueGraph(t)
+
+	pkg, ok := g.Value("c")
+	if !ok || pkg.name != "c" {
+		t.Fatal("unexpected value for c", pkg, ok)
+	}
+
+	// Put on existing node keeps its edges
+	g.Put("c", testPackage{name: "c", version: 2})
+	if pkg, _ = g.Value("c"); pkg.version != 2 || !g.DependsOnDirectly("c", "a") {
+		t.Fatal("Put should update value and keep edges")
+	}
+
+	// Put adds nodes like AddNode, so they can still be autoremoved
+	if reason := g.InstallReasonOf("c"); reason != soydepend.InstallDependency {
+		t.Fatal("unexpected install reason of c", reason.String())
+	}
+
+	removed := g.RemoveAutoRemove("d")
+	g.AssertRelationships()
+	assertValues(t, removed, "d", "c")
+	if _, ok = g.Value("c"); ok {
+		t.Fatal("value of removed c should be dropped")
+	}
+
+	removed, err := g.Remove("b")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	assertValues(t, removed, "b")
+
+	removed = g.RemoveForce("x")
+	g.AssertRelationships()
+	assertValues(t, removed, "x", "y")
+
+	if values := g.Values(); !reflect.DeepEqual(values, map[string]testPackage{"a": {name: "a", version: 1}}) {
+		t.Fatal("unexpected remaining values", values)
+	}
+
+	// Values put before edges are added are removed with orphaned dependencies
+	g = soydepend.NewValueGraph[string, testPackage]()
+	g.Put("a", testPackage{name: "a"})
+	g.Put("b", testPackage{name: "b"})
+	if err := g.Depend("b", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	removed = g.RemoveAutoRemove("b")
+	assertValues(t, removed, "a", "b")
+	if values := g.Values(); len(values) != 0 {
+		t.Fatal("unexpected remaining values", values)
+	}
+}
+
+func TestValueGraphNoDrift(t *testing.T) {
+	g := initTestValueGraph(t)
+	clone := g.Clone()
+
+	// Removal via transaction on the embedded graph still drops values
+	err := g.Begin().RemoveForce("a").Delete("y").Commit()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	g.AssertRelationships()
+	if values := g.Values(); len(values) != 1 {
+		t.Fatal("unexpected remaining values", values)
+	}
+
+	// Nodes deleted and added back in one transaction still lose their values
+	err = g.Begin().Delete("x").AddNode("x").Commit()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, ok := g.Value("x"); ok || !g.Contains("x") {
+		t.Fatal("x should be back without its value")
+	}
+
+	// Clone has its own values
+	clone.AssertRelationships()
+	if values := clone.Values(); len(values) != 6 {
+		t.Fatal("clone values changed", values)
+	}
+
+	clone.Realloc()
+	plan, err := clone.PlanRemoveAutoRemove("y")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	removed, err := clone.ApplyPlan(plan)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	clone.AssertRelationships()
+	assertValues(t, removed, "x", "y")
+
+	value, ok := clone.Delete("a")
+	if !ok || value.name != "a" {
+		t.Fatal("unexpected deleted value", value, ok)
+	}
+
+	clone.AssertRelationships()
+}
+
+func assertValues(t *testing.T, values map[string]testPackage, keys ...string) {
+	if len(values) != len(keys) {
+		t.Fatalf("expecting values for %v, got %v", keys, values)
+	}
+
+	for _, key := range keys {
+		if values[key].name != key {
+			t.Fatalf("unexpected value for %s: %v", key, values[key])
+		}
+	}
+}